@@ -0,0 +1,205 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// BodyReplay defines how a request body of type io.Reader is prepared so it can be sent
+// more than once, e.g. for retries, 307/308 redirects or when an HTTP/2 connection is
+// shut down by the server. Bodies that are encoded by the package as well as readers of
+// type *bytes.Buffer, *bytes.Reader and *strings.Reader can always be replayed.
+type BodyReplay int
+
+const (
+	// BodyReplayNone sends the reader as it is. The request body cannot be sent again.
+	BodyReplayNone BodyReplay = iota
+	// BodyReplayMemory buffers the body in memory up to MaxBodyBufferBytes.
+	// Larger bodies are streamed and cannot be sent again.
+	BodyReplayMemory
+	// BodyReplayTempFile buffers the body in memory up to MaxBodyBufferBytes.
+	// Larger bodies are written to a temporary file that is removed after the request.
+	BodyReplayTempFile
+)
+
+// defaultMaxBodyBufferBytes is the in-memory size limit for replayable bodies if there is none provided.
+const defaultMaxBodyBufferBytes = 1 << 20
+
+// requestBody provides the request body for every attempt of a request.
+type requestBody struct {
+	// reader is the remaining body if it cannot be replayed.
+	reader io.Reader
+	// data holds the body if it was buffered in memory.
+	data []byte
+	// file holds the body if it was written to a temporary file.
+	file *os.File
+	size int64
+	// contentType overrides the content type of the codec if it is set.
+	contentType string
+	// contentEncoding is set if the body was compressed.
+	contentEncoding string
+	// pipe is closed once the request is done to stop the writer of a streamed body.
+	pipe *io.PipeReader
+}
+
+// prepareBody encodes the body of the request params or makes the provided reader replayable
+// as configured. The returned body must be closed once the request is done.
+func prepareBody(params Params, codec Codec) (*requestBody, error) {
+	switch body := params.Body.(type) {
+	case nil:
+		return &requestBody{}, nil
+	case *bytes.Buffer:
+		return &requestBody{data: body.Bytes(), size: int64(body.Len())}, nil
+	case *bytes.Reader, *strings.Reader:
+		// Those readers are already held in memory so reading them does not cost much.
+		data, err := ioutil.ReadAll(body.(io.Reader))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		return &requestBody{data: data, size: int64(len(data))}, nil
+	case io.Reader:
+		return bufferBody(body, params.BodyReplay, params.MaxBodyBufferBytes)
+	case FormBody:
+		return encodeBody(FormCodec{}, body.Value, true)
+	case *Multipart:
+		reader, contentType := body.reader()
+		return &requestBody{reader: reader, pipe: reader, contentType: contentType}, nil
+	}
+
+	return encodeBody(codec, params.Body, false)
+}
+
+// encodeBody encodes the value with the codec. If setContentType is true, the content type
+// of the codec is used for the request instead of the one of the client or params.
+func encodeBody(codec Codec, value interface{}, setContentType bool) (*requestBody, error) {
+	buffer := &bytes.Buffer{}
+	err := codec.Encode(buffer, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request body to %s: %w", formatName(codec.ContentType()), err)
+	}
+
+	body := &requestBody{data: buffer.Bytes(), size: int64(buffer.Len())}
+	if setContentType {
+		body.contentType = codec.ContentType()
+	}
+
+	return body, nil
+}
+
+// bufferBody reads the reader into memory or into a temporary file depending on the replay mode.
+func bufferBody(reader io.Reader, mode BodyReplay, maxBytes int64) (*requestBody, error) {
+	if mode == BodyReplayNone {
+		return &requestBody{reader: reader}, nil
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBodyBufferBytes
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	if int64(len(data)) <= maxBytes {
+		return &requestBody{data: data, size: int64(len(data))}, nil
+	}
+
+	rest := io.MultiReader(bytes.NewReader(data), reader)
+	if mode == BodyReplayMemory {
+		return &requestBody{reader: rest}, nil
+	}
+
+	file, err := ioutil.TempFile("", "go-request-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for request body: %w", err)
+	}
+
+	body := &requestBody{file: file}
+	body.size, err = io.Copy(file, rest)
+	if err != nil {
+		_ = body.close()
+		return nil, fmt.Errorf("failed to write request body to temporary file: %w", err)
+	}
+
+	return body, nil
+}
+
+// replayable reports whether the body can be sent more than once.
+func (b *requestBody) replayable() bool {
+	return b.reader == nil
+}
+
+// getBody returns a new reader of the full body. It is used as http.Request.GetBody.
+func (b *requestBody) getBody() (io.ReadCloser, error) {
+	if b.file != nil {
+		return ioutil.NopCloser(io.NewSectionReader(b.file, 0, b.size)), nil
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(b.data)), nil
+}
+
+// attach sets the body of the request. If the body is replayable, GetBody is set
+// so the http client can send it again if necessary.
+func (b *requestBody) attach(req *http.Request) error {
+	if !b.replayable() {
+		readCloser, ok := b.reader.(io.ReadCloser)
+		if !ok {
+			readCloser = ioutil.NopCloser(b.reader)
+		}
+		req.Body = readCloser
+		return nil
+	}
+
+	if b.size == 0 && b.file == nil && b.data == nil {
+		return nil
+	}
+
+	body, err := b.getBody()
+	if err != nil {
+		return err
+	}
+
+	req.Body = body
+	req.GetBody = b.getBody
+	req.ContentLength = b.size
+	if b.size == 0 {
+		req.Body = http.NoBody
+	}
+
+	return nil
+}
+
+// close removes the temporary file if there is one and stops the writer of a streamed body.
+func (b *requestBody) close() error {
+	if b.pipe != nil {
+		_ = b.pipe.Close()
+	}
+
+	if b.file == nil {
+		return nil
+	}
+
+	cErr := b.file.Close()
+	rErr := os.Remove(b.file.Name())
+	if cErr != nil {
+		return cErr
+	}
+
+	return rErr
+}
+
+// formatName derives a short format name like "json" from a media type like "application/json".
+func formatName(contentType string) string {
+	name := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if i := strings.LastIndexAny(name, "/+"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
@@ -0,0 +1,180 @@
+package request
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// onlyReader hides the concrete type of the reader so it is not recognised as in-memory reader.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestPrepareBody(t *testing.T) {
+	t.Run("encoded body sets GetBody", func(t *testing.T) {
+		body, err := prepareBody(Params{Body: Input{RequestValue: "someValueIn"}}, JSONCodec{})
+		require.NoError(t, err)
+
+		req, err := NewClient().createRequest(context.Background(), Params{URL: "http://example.com"}, body)
+		require.NoError(t, err)
+		require.NotNil(t, req.GetBody)
+		assert.Equal(t, int64(31), req.ContentLength)
+
+		for i := 0; i < 2; i++ {
+			reader, err := req.GetBody()
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, `{"requestValue":"someValueIn"}`+"\n", string(data))
+		}
+	})
+
+	t.Run("reader is not replayable by default", func(t *testing.T) {
+		body, err := prepareBody(Params{Body: onlyReader{strings.NewReader("abc")}}, JSONCodec{})
+		require.NoError(t, err)
+		assert.False(t, body.replayable())
+	})
+
+	t.Run("memory buffering", func(t *testing.T) {
+		body, err := prepareBody(Params{Body: onlyReader{strings.NewReader("abc")}, BodyReplay: BodyReplayMemory}, JSONCodec{})
+		require.NoError(t, err)
+		assert.True(t, body.replayable())
+		assert.Equal(t, int64(3), body.size)
+	})
+
+	t.Run("memory buffering above the limit", func(t *testing.T) {
+		params := Params{
+			Body:               onlyReader{strings.NewReader("abcdef")},
+			BodyReplay:         BodyReplayMemory,
+			MaxBodyBufferBytes: 3,
+		}
+		body, err := prepareBody(params, JSONCodec{})
+		require.NoError(t, err)
+		assert.False(t, body.replayable())
+
+		data, err := ioutil.ReadAll(body.reader)
+		require.NoError(t, err)
+		assert.Equal(t, "abcdef", string(data))
+	})
+
+	t.Run("temporary file above the limit", func(t *testing.T) {
+		params := Params{
+			Body:               onlyReader{strings.NewReader("abcdef")},
+			BodyReplay:         BodyReplayTempFile,
+			MaxBodyBufferBytes: 3,
+		}
+		body, err := prepareBody(params, JSONCodec{})
+		require.NoError(t, err)
+		require.NotNil(t, body.file)
+		assert.True(t, body.replayable())
+		assert.Equal(t, int64(6), body.size)
+
+		for i := 0; i < 2; i++ {
+			reader, err := body.getBody()
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, "abcdef", string(data))
+		}
+
+		fileName := body.file.Name()
+		require.NoError(t, body.close())
+		_, err = os.Stat(fileName)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestReplayBodyOnRetry(t *testing.T) {
+	newServer := func(calls *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "some body content", string(body))
+			if atomic.AddInt32(calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+	}
+
+	tests := map[string]struct {
+		mode     BodyReplay
+		maxBytes int64
+	}{
+		"memory":         {mode: BodyReplayMemory},
+		"temporary file": {mode: BodyReplayTempFile, maxBytes: 4},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			calls := int32(0)
+			ts := newServer(&calls)
+			defer ts.Close()
+
+			params := Params{
+				URL:                ts.URL,
+				Method:             http.MethodPut,
+				Body:               onlyReader{strings.NewReader("some body content")},
+				BodyReplay:         test.mode,
+				MaxBodyBufferBytes: test.maxBytes,
+				Retry:              &RetryPolicy{BaseDelay: time.Millisecond},
+			}
+
+			err := Do(params, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		})
+	}
+
+	t.Run("not replayable", func(t *testing.T) {
+		calls := int32(0)
+		ts := newServer(&calls)
+		defer ts.Close()
+
+		params := Params{
+			URL:    ts.URL,
+			Method: http.MethodPut,
+			Body:   onlyReader{strings.NewReader("some body content")},
+			Retry:  &RetryPolicy{BaseDelay: time.Millisecond},
+		}
+
+		err := Do(params, nil)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestReplayBodyOnRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, err := w.Write([]byte(`{"responseValue":"` + string(body) + `"}`))
+		assert.NoError(t, err)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	params := Params{
+		URL:        ts.URL + "/old",
+		Method:     http.MethodPost,
+		Body:       onlyReader{strings.NewReader("someValueIn")},
+		BodyReplay: BodyReplayMemory,
+	}
+
+	result := &Output{}
+	err := DoWithCustomClient(params, result, &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, "someValueIn", result.ResponseValue)
+}
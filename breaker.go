@@ -0,0 +1,283 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned if a request was not sent because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Default values of the circuit breaker settings that are used for fields that are not set.
+const (
+	defaultConsecutiveFailures = 5
+	defaultMinRequests         = 10
+	defaultCoolDown            = 10 * time.Second
+	defaultHalfOpenProbes      = 1
+)
+
+// CircuitState is the state of a circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests pass.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cool-down period is over.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests pass to find out if the dependency recovered.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerSettings configures a circuit breaker.
+type CircuitBreakerSettings struct {
+	// ConsecutiveFailures opens the circuit after that many failures in a row. Defaults to 5.
+	ConsecutiveFailures int
+
+	// FailureRatio opens the circuit if the ratio of failed requests reaches the value.
+	// It is only evaluated once MinRequests requests were made. Zero disables the check.
+	FailureRatio float64
+
+	// MinRequests is the number of requests that is needed before the failure ratio is evaluated. Defaults to 10.
+	MinRequests int
+
+	// Interval resets the counts of a closed circuit periodically. If it is zero,
+	// the counts are only reset when the state changes.
+	Interval time.Duration
+
+	// CoolDown is the time the circuit stays open before probe requests are allowed. Defaults to 10 seconds.
+	CoolDown time.Duration
+
+	// HalfOpenProbes is the number of probe requests that are allowed in the half-open state.
+	// If all of them succeed, the circuit is closed again. Defaults to 1.
+	HalfOpenProbes int
+
+	// IsFailure decides if the result of a request counts as failure. err is the error
+	// that occurred while sending the request. By default errors and 5xx responses are failures.
+	// Requests that were cancelled by the caller count neither as failure nor as success.
+	IsFailure func(res *http.Response, err error) bool
+
+	// OnStateChange is called whenever a circuit changes its state.
+	OnStateChange func(key string, from CircuitState, to CircuitState)
+}
+
+// CircuitBreaker stops sending requests to a dependency after it failed repeatedly.
+// It keeps a separate circuit per key, by default the host of the request.
+// A circuit breaker can be shared between clients.
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+	// notifications are the state change callbacks that are called once the mutex is released.
+	notifications []func()
+}
+
+// circuit holds the state of a single key.
+type circuit struct {
+	state               CircuitState
+	generation          uint64
+	openedAt            time.Time
+	countSince          time.Time
+	requests            int
+	failures            int
+	successes           int
+	consecutiveFailures int
+	inFlight            int
+}
+
+// NewCircuitBreaker returns a circuit breaker with the given settings.
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	if settings.ConsecutiveFailures == 0 {
+		settings.ConsecutiveFailures = defaultConsecutiveFailures
+	}
+	if settings.MinRequests == 0 {
+		settings.MinRequests = defaultMinRequests
+	}
+	if settings.CoolDown == 0 {
+		settings.CoolDown = defaultCoolDown
+	}
+	if settings.HalfOpenProbes == 0 {
+		settings.HalfOpenProbes = defaultHalfOpenProbes
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = isCircuitFailure
+	}
+
+	return &CircuitBreaker{
+		settings: settings,
+		circuits: map[string]*circuit{},
+		now:      time.Now,
+	}
+}
+
+// State returns the current state of the circuit for the key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mutex.Lock()
+	defer b.unlock()
+
+	return b.circuit(key).state
+}
+
+// allow checks if a request for the key may be sent. If so, the returned function
+// must be called with the result of the request.
+func (b *CircuitBreaker) allow(key string) (func(res *http.Response, err error), error) {
+	b.mutex.Lock()
+	defer b.unlock()
+
+	c := b.circuit(key)
+	switch c.state {
+	case CircuitOpen:
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+	case CircuitHalfOpen:
+		if c.inFlight+c.successes >= b.settings.HalfOpenProbes {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+		}
+	}
+
+	c.inFlight++
+	generation := c.generation
+	return func(res *http.Response, err error) {
+		if errors.Is(err, context.Canceled) {
+			b.release(key, generation)
+			return
+		}
+		b.done(key, generation, b.settings.IsFailure(res, err))
+	}, nil
+}
+
+// release frees the slot of a request without recording its result.
+func (b *CircuitBreaker) release(key string, generation uint64) {
+	b.mutex.Lock()
+	defer b.unlock()
+
+	c := b.circuit(key)
+	if c.generation == generation {
+		c.inFlight--
+	}
+}
+
+// done records the result of a request. Results from an earlier state are ignored.
+func (b *CircuitBreaker) done(key string, generation uint64, failed bool) {
+	b.mutex.Lock()
+	defer b.unlock()
+
+	c := b.circuit(key)
+	if c.generation != generation {
+		return
+	}
+
+	c.inFlight--
+	c.requests++
+	if failed {
+		c.failures++
+		c.consecutiveFailures++
+	} else {
+		c.successes++
+		c.consecutiveFailures = 0
+	}
+
+	switch {
+	case c.state == CircuitHalfOpen && failed:
+		b.setState(key, c, CircuitOpen)
+	case c.state == CircuitHalfOpen && c.successes >= b.settings.HalfOpenProbes:
+		b.setState(key, c, CircuitClosed)
+	case c.state == CircuitClosed && b.shouldOpen(c):
+		b.setState(key, c, CircuitOpen)
+	}
+}
+
+// circuit returns the circuit of the key and advances its state based on the time.
+// The mutex must be held by the caller.
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	now := b.now()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{countSince: now}
+		b.circuits[key] = c
+	}
+
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= b.settings.CoolDown {
+		b.setState(key, c, CircuitHalfOpen)
+	}
+
+	if c.state == CircuitClosed && b.settings.Interval > 0 && now.Sub(c.countSince) >= b.settings.Interval {
+		c.reset(now)
+	}
+
+	return c
+}
+
+func (b *CircuitBreaker) shouldOpen(c *circuit) bool {
+	if c.consecutiveFailures >= b.settings.ConsecutiveFailures {
+		return true
+	}
+
+	return b.settings.FailureRatio > 0 && c.requests >= b.settings.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= b.settings.FailureRatio
+}
+
+// setState changes the state of the circuit and resets its counts.
+// The mutex must be held by the caller.
+func (b *CircuitBreaker) setState(key string, c *circuit, state CircuitState) {
+	previous := c.state
+	now := b.now()
+	c.state = state
+	c.generation++
+	c.inFlight = 0
+	c.reset(now)
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+
+	if b.settings.OnStateChange != nil {
+		b.notifications = append(b.notifications, func() {
+			b.settings.OnStateChange(key, previous, state)
+		})
+	}
+}
+
+// unlock releases the mutex and calls the pending state change callbacks.
+func (b *CircuitBreaker) unlock() {
+	notifications := b.notifications
+	b.notifications = nil
+	b.mutex.Unlock()
+
+	for _, notify := range notifications {
+		notify()
+	}
+}
+
+func (c *circuit) reset(now time.Time) {
+	c.countSince = now
+	c.requests = 0
+	c.failures = 0
+	c.successes = 0
+	c.consecutiveFailures = 0
+}
+
+// isCircuitFailure treats errors and server errors as failures.
+func isCircuitFailure(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,218 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock allows to control the time of the circuit breaker in tests.
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCircuitBreaker(settings CircuitBreakerSettings) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)}
+	breaker := NewCircuitBreaker(settings)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failing := int32(1)
+	calls := int32(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	changes := []string{}
+	breaker, clock := newTestCircuitBreaker(CircuitBreakerSettings{
+		ConsecutiveFailures: 3,
+		CoolDown:            time.Minute,
+		OnStateChange: func(key string, from CircuitState, to CircuitState) {
+			assert.Equal(t, u.Host, key)
+			changes = append(changes, from.String()+" -> "+to.String())
+		},
+	})
+
+	client := NewClient()
+	client.CircuitBreaker = breaker
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := client.Get(ctx, ts.URL, nil)
+		assert.False(t, errors.Is(err, ErrCircuitOpen))
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(u.Host))
+
+	t.Run("fails fast while open", func(t *testing.T) {
+		err := client.Get(ctx, ts.URL, nil)
+		assert.True(t, errors.Is(err, ErrCircuitOpen))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("failed probe opens the circuit again", func(t *testing.T) {
+		clock.Advance(time.Minute)
+		assert.Equal(t, CircuitHalfOpen, breaker.State(u.Host))
+
+		err := client.Get(ctx, ts.URL, nil)
+		assert.False(t, errors.Is(err, ErrCircuitOpen))
+		assert.Equal(t, CircuitOpen, breaker.State(u.Host))
+	})
+
+	t.Run("successful probe closes the circuit", func(t *testing.T) {
+		atomic.StoreInt32(&failing, 0)
+		clock.Advance(time.Minute)
+
+		err := client.Get(ctx, ts.URL, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, CircuitClosed, breaker.State(u.Host))
+	})
+
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, changes)
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(CircuitBreakerSettings{ConsecutiveFailures: 1, HalfOpenProbes: 2})
+
+	done, err := breaker.allow("key")
+	require.NoError(t, err)
+	done(nil, errors.New("failed"))
+	assert.Equal(t, CircuitOpen, breaker.State("key"))
+
+	clock.Advance(defaultCoolDown)
+	probe1, err := breaker.allow("key")
+	require.NoError(t, err)
+	probe2, err := breaker.allow("key")
+	require.NoError(t, err)
+	_, err = breaker.allow("key")
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	probe1(&http.Response{StatusCode: http.StatusOK}, nil)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("key"))
+	probe2(&http.Response{StatusCode: http.StatusOK}, nil)
+	assert.Equal(t, CircuitClosed, breaker.State("key"))
+}
+
+func TestCircuitBreakerCancelledRequests(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(CircuitBreakerSettings{ConsecutiveFailures: 2})
+
+	record := func(err error) {
+		done, allowErr := breaker.allow("key")
+		require.NoError(t, allowErr)
+		done(nil, err)
+	}
+
+	t.Run("closed circuit keeps the consecutive failures", func(t *testing.T) {
+		record(errors.New("failed"))
+		record(fmt.Errorf("failed to send request: %w", context.Canceled))
+		assert.Equal(t, CircuitClosed, breaker.State("key"))
+		record(errors.New("failed"))
+		assert.Equal(t, CircuitOpen, breaker.State("key"))
+	})
+
+	t.Run("cancelled probe does not close the circuit", func(t *testing.T) {
+		clock.Advance(defaultCoolDown)
+		probe, err := breaker.allow("key")
+		require.NoError(t, err)
+		probe(nil, context.Canceled)
+		assert.Equal(t, CircuitHalfOpen, breaker.State("key"))
+
+		probe, err = breaker.allow("key")
+		require.NoError(t, err)
+		probe(nil, errors.New("failed"))
+		assert.Equal(t, CircuitOpen, breaker.State("key"))
+	})
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(CircuitBreakerSettings{
+		ConsecutiveFailures: 100,
+		FailureRatio:        0.5,
+		MinRequests:         4,
+		Interval:            time.Minute,
+	})
+
+	record := func(failed bool) {
+		done, err := breaker.allow("key")
+		require.NoError(t, err)
+		if failed {
+			done(&http.Response{StatusCode: http.StatusBadGateway}, nil)
+		} else {
+			done(&http.Response{StatusCode: http.StatusOK}, nil)
+		}
+	}
+
+	record(true)
+	record(false)
+	record(true)
+	assert.Equal(t, CircuitClosed, breaker.State("key"))
+
+	// The counts are reset after the interval.
+	clock.Advance(time.Minute)
+	record(false)
+	record(true)
+	record(false)
+	record(true)
+	assert.Equal(t, CircuitOpen, breaker.State("key"))
+}
+
+func TestCircuitBreakerKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerSettings{ConsecutiveFailures: 1})
+	client := NewClient()
+	client.CircuitBreaker = breaker
+
+	err := client.Do(context.Background(), Params{URL: ts.URL, CircuitBreakerKey: "partner-api"}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State("partner-api"))
+
+	err = client.Do(context.Background(), Params{URL: ts.URL, CircuitBreakerKey: "partner-api"}, nil)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+}
+
+func TestIsCircuitFailure(t *testing.T) {
+	assert.True(t, isCircuitFailure(nil, errors.New("connection refused")))
+	assert.True(t, isCircuitFailure(&http.Response{StatusCode: http.StatusInternalServerError}, nil))
+	assert.False(t, isCircuitFailure(&http.Response{StatusCode: http.StatusNotFound}, nil))
+}
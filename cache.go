@@ -0,0 +1,417 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CacheStatusHeader is set on all responses that passed through a Cache. Its value is one of
+// CacheHit, CacheStale, CacheRevalidated or CacheMiss.
+const CacheStatusHeader = "X-Cache-Status"
+
+// Values of the CacheStatusHeader.
+const (
+	// CacheHit means the response was fresh and served from the cache.
+	CacheHit = "HIT"
+	// CacheStale means a stale response was served from the cache because of max-stale,
+	// stale-while-revalidate or stale-if-error.
+	CacheStale = "STALE"
+	// CacheRevalidated means the server confirmed that the stored response is still valid.
+	CacheRevalidated = "REVALIDATED"
+	// CacheMiss means the response was received from the server.
+	CacheMiss = "MISS"
+)
+
+// Default values of the cache settings that are used for fields that are not set.
+const (
+	defaultCacheBytes          = 10 << 20
+	defaultMaxEntryBytes       = 1 << 20
+	defaultRevalidationTimeout = 30 * time.Second
+)
+
+// maxCacheVariants is the number of responses that are stored for a URL, e.g. for different credentials
+// or values of the Vary headers. The least recently stored ones are dropped first.
+const maxCacheVariants = 16
+
+// CacheSettings configures a cache.
+type CacheSettings struct {
+	// Storage holds the cached responses. Defaults to a memory cache with a limit of 10 MiB.
+	Storage CacheStorage
+
+	// Shared makes the cache behave like a shared cache, e.g. a proxy: responses marked as private
+	// and most responses to requests with an Authorization header are not stored and s-maxage is used.
+	// By default the cache is private to the client like the cache of a browser and the responses
+	// to requests with different Authorization headers are stored separately.
+	Shared bool
+
+	// MaxEntryBytes is the size of the largest response body that is stored. Defaults to 1 MiB.
+	MaxEntryBytes int64
+
+	// RevalidationTimeout limits the duration of revalidations in the background
+	// that are triggered by stale-while-revalidate. Defaults to 30 seconds.
+	RevalidationTimeout time.Duration
+}
+
+// Cache stores responses to GET requests as defined in RFC 9111 and serves them as long as they are fresh.
+// Stale responses are revalidated with the ETag and Last-Modified headers. The directives
+// stale-while-revalidate and stale-if-error of RFC 5861 are supported as well.
+// Successful requests with other methods invalidate the stored responses of their URL.
+// A cache can be shared between clients. Unless it is a shared cache, the responses are only served
+// to requests with the same Authorization header, which must be set before the cache middleware runs.
+type Cache struct {
+	settings CacheSettings
+	now      func() time.Time
+	mutex    sync.Mutex
+	// revalidating holds the variants that are revalidated in the background.
+	revalidating map[string]bool
+	// storageMutex serializes the updates of the variants that are stored for a URL.
+	storageMutex sync.Mutex
+}
+
+// NewCache returns a cache with the given settings.
+func NewCache(settings CacheSettings) *Cache {
+	if settings.Storage == nil {
+		settings.Storage = NewMemoryCache(defaultCacheBytes)
+	}
+	if settings.MaxEntryBytes == 0 {
+		settings.MaxEntryBytes = defaultMaxEntryBytes
+	}
+	if settings.RevalidationTimeout == 0 {
+		settings.RevalidationTimeout = defaultRevalidationTimeout
+	}
+
+	return &Cache{
+		settings:     settings,
+		now:          time.Now,
+		revalidating: map[string]bool{},
+	}
+}
+
+// Middleware returns the middleware that serves the requests from the cache. Middlewares that run before
+// the cache middleware apply to cached responses as well, the ones that run after it only to requests that
+// are sent to the server. Requests with their own conditional headers bypass the cache.
+func (c *Cache) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return c.handle(req, next)
+		}
+	}
+}
+
+func (c *Cache) handle(req *http.Request, next Handler) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		res, err := next(req)
+		c.invalidate(req, res, err)
+		return res, err
+	}
+
+	control := parseCacheControl(req.Header)
+	if control.has("no-store") || hasConditionalHeaders(req) {
+		return next(req)
+	}
+
+	key := cacheKey(req.URL)
+	entry := c.load(key, req)
+	if entry == nil {
+		if control.has("only-if-cached") {
+			return gatewayTimeout(req), nil
+		}
+		return c.fetch(req, next, key)
+	}
+
+	return c.serve(req, next, key, entry, control)
+}
+
+// serve returns the stored response if it is usable and revalidates it otherwise.
+func (c *Cache) serve(req *http.Request, next Handler, key string, entry *cacheEntry, control cacheControl) (*http.Response, error) {
+	now := c.now()
+	usable, stale := entry.usable(now, control, c.settings.Shared)
+	switch {
+	case usable && stale:
+		return entry.response(req, now, CacheStale), nil
+	case usable:
+		return entry.response(req, now, CacheHit), nil
+	case control.has("only-if-cached"):
+		return gatewayTimeout(req), nil
+	case !control.has("no-cache") && entry.staleWithin(now, "stale-while-revalidate", cacheControl{}, c.settings.Shared):
+		res := entry.response(req, now, CacheStale)
+		c.revalidateInBackground(req, next, key, entry)
+		return res, nil
+	}
+
+	return c.revalidate(req, next, key, entry, control)
+}
+
+// fetch sends the request and stores the response if it is cacheable.
+func (c *Cache) fetch(req *http.Request, next Handler, key string) (*http.Response, error) {
+	requestTime := c.now()
+	res, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.store(req, res, key, requestTime)
+}
+
+// revalidate sends a conditional request for the stored response. If the server confirms that the response
+// is still valid, it is served from the cache. Otherwise the new response is stored and returned.
+// If the server can not be reached, the stored response is served if stale-if-error allows it.
+func (c *Cache) revalidate(req *http.Request, next Handler, key string, entry *cacheEntry, control cacheControl) (*http.Response, error) {
+	conditional := req.Clone(req.Context())
+	entry.setValidators(conditional)
+
+	requestTime := c.now()
+	res, err := next(conditional)
+	if (err != nil || isServerError(res.StatusCode)) && entry.staleWithin(c.now(), "stale-if-error", control, c.settings.Shared) {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+		return entry.response(req, c.now(), CacheStale), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusNotModified {
+		return c.store(req, res, key, requestTime)
+	}
+
+	_ = res.Body.Close()
+	entry.update(res.Header, requestTime, c.now())
+	c.save(key, req, entry)
+	return entry.response(req, c.now(), CacheRevalidated), nil
+}
+
+// revalidateInBackground revalidates the stored response unless that already happens for its variant.
+// The request is detached from the context of the caller, which might end before the revalidation did.
+func (c *Cache) revalidateInBackground(req *http.Request, next Handler, key string, entry *cacheEntry) {
+	variant := key + "\n" + entry.variant()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.revalidating[variant] {
+		return
+	}
+	c.revalidating[variant] = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.settings.RevalidationTimeout)
+	detached := req.Clone(ctx)
+	go func() {
+		defer func() {
+			cancel()
+			c.mutex.Lock()
+			delete(c.revalidating, variant)
+			c.mutex.Unlock()
+		}()
+
+		res, err := c.revalidate(detached, next, key, entry, cacheControl{})
+		if err == nil {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+	}()
+}
+
+// store saves the response if it is cacheable. The body is read into memory for that unless
+// it is larger than MaxEntryBytes, in which case the response is passed on without storing it.
+func (c *Cache) store(req *http.Request, res *http.Response, key string, requestTime time.Time) (*http.Response, error) {
+	res.Header.Set(CacheStatusHeader, CacheMiss)
+	if !c.isStorable(req, res) {
+		return res, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, c.settings.MaxEntryBytes+1))
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if int64(len(body)) > c.settings.MaxEntryBytes {
+		res.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(body), res.Body), Closer: res.Body}
+		return res, nil
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := newCacheEntry(req, res, body, requestTime, c.now())
+	entry.Header.Del(CacheStatusHeader)
+	entry.Credentials = c.credentials(req)
+	c.save(key, req, entry)
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// isStorable checks if the response may be stored as defined in RFC 9111 section 3.
+func (c *Cache) isStorable(req *http.Request, res *http.Response) bool {
+	control := parseCacheControl(res.Header)
+	if control.has("no-store") || res.StatusCode == http.StatusPartialContent || res.StatusCode == http.StatusNotModified {
+		return false
+	}
+
+	for _, name := range varyHeaders(res.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+
+	if c.settings.Shared && !c.isStorableByShared(req, control) {
+		return false
+	}
+
+	return hasExplicitExpiration(res.Header, control) || heuristicallyCacheable[res.StatusCode]
+}
+
+// isStorableByShared checks the additional rules for shared caches.
+func (c *Cache) isStorableByShared(req *http.Request, control cacheControl) bool {
+	if control.has("private") {
+		return false
+	}
+
+	if req.Header.Get("Authorization") == "" {
+		return true
+	}
+
+	return control.has("public") || control.has("must-revalidate") || control.has("s-maxage")
+}
+
+// load returns the stored variant of the key that matches the credentials and the Vary headers of the request.
+func (c *Cache) load(key string, req *http.Request) *cacheEntry {
+	credentials := c.credentials(req)
+	for _, entry := range c.variants(key) {
+		if entry.matches(req, credentials) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// save stores the entry as variant for the request. It replaces the stored variants that match the request.
+func (c *Cache) save(key string, req *http.Request, entry *cacheEntry) {
+	c.storageMutex.Lock()
+	defer c.storageMutex.Unlock()
+
+	variants := []*cacheEntry{entry}
+	credentials := c.credentials(req)
+	for _, variant := range c.variants(key) {
+		if len(variants) < maxCacheVariants && !variant.matches(req, credentials) {
+			variants = append(variants, variant)
+		}
+	}
+
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return
+	}
+
+	c.settings.Storage.Set(key, data)
+}
+
+// variants returns the responses stored for the key, the most recently stored one first.
+func (c *Cache) variants(key string) []*cacheEntry {
+	data, ok := c.settings.Storage.Get(key)
+	if !ok {
+		return nil
+	}
+
+	variants := []*cacheEntry{}
+	if json.Unmarshal(data, &variants) != nil {
+		return nil
+	}
+
+	return variants
+}
+
+// credentials returns a hash of the Authorization header of the request. It is empty for requests without
+// the header and in a shared cache, which only stores responses to them that may be served to everyone.
+func (c *Cache) credentials(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	if c.settings.Shared || authorization == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])
+}
+
+// invalidate removes all stored responses of the URLs affected by a successful request with an unsafe method,
+// regardless of the credentials they were stored for.
+func (c *Cache) invalidate(req *http.Request, res *http.Response, err error) {
+	if err != nil || res.StatusCode >= http.StatusBadRequest || isSafeMethod(req.Method) {
+		return
+	}
+
+	c.storageMutex.Lock()
+	defer c.storageMutex.Unlock()
+
+	c.settings.Storage.Delete(cacheKey(req.URL))
+	for _, name := range []string{"Location", "Content-Location"} {
+		location, err := req.URL.Parse(res.Header.Get(name))
+		if err == nil && res.Header.Get(name) != "" && location.Host == req.URL.Host {
+			c.settings.Storage.Delete(cacheKey(location))
+		}
+	}
+}
+
+// cacheKey returns the URL without user info and fragment.
+func cacheKey(u *url.URL) string {
+	key := *u
+	key.User = nil
+	key.Fragment = ""
+	key.RawFragment = ""
+	return key.String()
+}
+
+func hasExplicitExpiration(header http.Header, control cacheControl) bool {
+	return control.has("max-age") || control.has("s-maxage") || header.Get("Expires") != ""
+}
+
+func hasConditionalHeaders(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+func isServerError(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError
+}
+
+// gatewayTimeout is the response to requests with only-if-cached that can not be served from the cache.
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "504 " + http.StatusText(http.StatusGatewayTimeout),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{CacheStatusHeader: []string{CacheMiss}},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// prefixedBody reads the part of the body that was already consumed before the rest of it.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
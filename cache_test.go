@@ -0,0 +1,388 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(settings CacheSettings) (*Client, *Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cache := NewCache(settings)
+	cache.now = clock.Now
+	client := NewClient()
+	client.Middlewares = []Middleware{cache.Middleware()}
+	return client, cache, clock
+}
+
+// newCacheServer returns a server that sets the Date header from the clock and counts the requests.
+func newCacheServer(clock *fakeClock, calls *int32, handler func(w http.ResponseWriter, r *http.Request, call int32)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", clock.Now().Format(http.TimeFormat))
+		handler(w, r, atomic.AddInt32(calls, 1))
+	}))
+}
+
+// cachedGet returns the cache status and the body of the response.
+func cachedGet(client *Client, params Params) (string, string, error) {
+	params.RetainResponseBody = true
+	res, err := client.DoResponse(context.Background(), params, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	return res.Header.Get(CacheStatusHeader), string(res.RawBody), nil
+}
+
+func TestCacheFreshness(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Age", "30")
+		case "/expires":
+			w.Header().Set("Expires", clock.Now().Add(time.Minute).Format(http.TimeFormat))
+		case "/heuristic":
+			w.Header().Set("Last-Modified", clock.Now().Add(-10*time.Minute).Format(http.TimeFormat))
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		_, _ = fmt.Fprintf(w, "response %d", call)
+	})
+	defer ts.Close()
+
+	tests := map[string]time.Duration{
+		"/max-age":   30 * time.Second,
+		"/expires":   time.Minute,
+		"/heuristic": time.Minute,
+	}
+	for path, lifetime := range tests {
+		t.Run(path, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			status, body, err := cachedGet(client, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			assert.Equal(t, CacheMiss, status)
+			assert.Equal(t, "response 1", body)
+
+			clock.Advance(lifetime - time.Second)
+			status, body, err = cachedGet(client, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			assert.Equal(t, CacheHit, status)
+			assert.Equal(t, "response 1", body)
+
+			clock.Advance(time.Second)
+			status, body, err = cachedGet(client, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			assert.Equal(t, CacheMiss, status)
+			assert.Equal(t, "response 2", body)
+		})
+	}
+
+	t.Run("no-store", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		for i := 0; i < 2; i++ {
+			status, _, err := cachedGet(client, Params{URL: ts.URL + "/no-store"})
+			require.NoError(t, err)
+			assert.Equal(t, CacheMiss, status)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("age header", func(t *testing.T) {
+		clock.Advance(time.Hour)
+		_, _, err := cachedGet(client, Params{URL: ts.URL + "/max-age"})
+		require.NoError(t, err)
+		clock.Advance(10 * time.Second)
+
+		res, err := client.DoResponse(context.Background(), Params{URL: ts.URL + "/max-age"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "40", res.Header.Get("Age"))
+	})
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{})
+	calls := int32(0)
+	lastModified := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("X-Call", fmt.Sprint(call))
+		if r.URL.Path == "/etag" {
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		} else {
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		_, _ = w.Write([]byte("content"))
+	})
+	defer ts.Close()
+
+	for _, path := range []string{"/etag", "/last-modified"} {
+		t.Run(path, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			_, _, err := cachedGet(client, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+
+			clock.Advance(20 * time.Second)
+			params := Params{URL: ts.URL + path, RetainResponseBody: true}
+			res, err := client.DoResponse(context.Background(), params, nil)
+			require.NoError(t, err)
+			assert.Equal(t, CacheRevalidated, res.Header.Get(CacheStatusHeader))
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "content", string(res.RawBody))
+			assert.Equal(t, "2", res.Header.Get("X-Call"))
+
+			status, _, err := cachedGet(client, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			assert.Equal(t, CacheHit, status)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		})
+	}
+
+	t.Run("request directives", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		_, _, err := cachedGet(client, Params{URL: ts.URL + "/etag", Headers: map[string]string{"Cache-Control": "no-cache"}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		clock.Advance(20 * time.Second)
+		status, _, err := cachedGet(client, Params{URL: ts.URL + "/etag", Headers: map[string]string{"Cache-Control": "max-stale=15"}})
+		require.NoError(t, err)
+		assert.Equal(t, CacheStale, status)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		_, _, err = cachedGet(client, Params{URL: ts.URL + "/uncached", Headers: map[string]string{"Cache-Control": "only-if-cached"}})
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusGatewayTimeout, responseErr.StatusCode)
+	})
+}
+
+func TestCacheStale(t *testing.T) {
+	client, cache, clock := newTestCache(CacheSettings{})
+	calls := int32(0)
+	failing := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=30, stale-if-error=60")
+		if r.URL.Path == "/must-revalidate" {
+			w.Header().Set("Cache-Control", "max-age=10, stale-if-error=60, must-revalidate")
+		}
+		_, _ = fmt.Fprintf(w, "response %d", call)
+	})
+	defer ts.Close()
+
+	t.Run("stale-while-revalidate", func(t *testing.T) {
+		_, _, err := cachedGet(client, Params{URL: ts.URL + "/swr"})
+		require.NoError(t, err)
+
+		clock.Advance(20 * time.Second)
+		status, body, err := cachedGet(client, Params{URL: ts.URL + "/swr"})
+		require.NoError(t, err)
+		assert.Equal(t, CacheStale, status)
+		assert.Equal(t, "response 1", body)
+
+		assert.Eventually(t, func() bool {
+			cache.mutex.Lock()
+			defer cache.mutex.Unlock()
+			return atomic.LoadInt32(&calls) == 2 && len(cache.revalidating) == 0
+		}, time.Second, time.Millisecond)
+
+		status, body, err = cachedGet(client, Params{URL: ts.URL + "/swr"})
+		require.NoError(t, err)
+		assert.Equal(t, CacheHit, status)
+		assert.Equal(t, "response 2", body)
+	})
+
+	t.Run("stale-if-error", func(t *testing.T) {
+		atomic.StoreInt32(&failing, 0)
+		_, _, err := cachedGet(client, Params{URL: ts.URL + "/sie"})
+		require.NoError(t, err)
+		_, _, err = cachedGet(client, Params{URL: ts.URL + "/must-revalidate"})
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&failing, 1)
+		clock.Advance(50 * time.Second)
+		status, body, err := cachedGet(client, Params{URL: ts.URL + "/sie"})
+		require.NoError(t, err)
+		assert.Equal(t, CacheStale, status)
+		assert.Contains(t, body, "response")
+
+		_, _, err = cachedGet(client, Params{URL: ts.URL + "/must-revalidate"})
+		assert.Error(t, err)
+
+		clock.Advance(30 * time.Second)
+		_, _, err = cachedGet(client, Params{URL: ts.URL + "/sie"})
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusServiceUnavailable, responseErr.StatusCode)
+	})
+}
+
+func TestCacheVary(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+	defer ts.Close()
+
+	german := Params{URL: ts.URL, Headers: map[string]string{"Accept-Language": "de"}}
+	english := Params{URL: ts.URL, Headers: map[string]string{"Accept-Language": "en"}}
+	for _, params := range []Params{german, german, english, english, german} {
+		_, body, err := cachedGet(client, params)
+		require.NoError(t, err)
+		assert.Equal(t, params.Headers["Accept-Language"], body)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCacheShared(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{Shared: true})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", strings.TrimPrefix(r.URL.Path, "/"))
+	})
+	defer ts.Close()
+
+	tests := []struct {
+		path          string
+		authorization bool
+		cached        bool
+	}{
+		{"/max-age=60", false, true},
+		{"/private,max-age=60", false, false},
+		{"/s-maxage=60,max-age=0", false, true},
+		{"/max-age=60", true, false},
+		{"/public,max-age=60", true, true},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&calls, 0)
+		params := Params{URL: ts.URL + test.path}
+		if test.authorization {
+			params.Headers = map[string]string{"Authorization": "Bearer token"}
+		}
+		for i := 0; i < 2; i++ {
+			_, _, err := cachedGet(client, params)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, test.cached, atomic.LoadInt32(&calls) == 1, test.path)
+	}
+}
+
+func TestCacheCredentials(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cache := NewCache(CacheSettings{})
+	cache.now = clock.Now
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%s %d", r.Header.Get("Authorization"), call)
+	})
+	defer ts.Close()
+
+	newUserClient := func(token string) *Client {
+		client := NewClient()
+		client.Middlewares = []Middleware{BearerAuth(token), cache.Middleware()}
+		return client
+	}
+	alice, bob := newUserClient("alice"), newUserClient("bob")
+
+	_, body, err := cachedGet(alice, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer alice 1", body)
+
+	status, body, err := cachedGet(bob, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, status)
+	assert.Equal(t, "Bearer bob 2", body)
+
+	status, body, err = cachedGet(alice, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, status)
+	assert.Equal(t, "Bearer alice 1", body)
+
+	err = alice.Do(context.Background(), Params{URL: ts.URL, Method: http.MethodPut, Body: Input{}}, nil)
+	require.NoError(t, err)
+	status, body, err = cachedGet(alice, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, status)
+	assert.Equal(t, "Bearer alice 4", body)
+
+	// The update of alice invalidated the responses stored for all credentials.
+	status, body, err = cachedGet(bob, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, status)
+	assert.Equal(t, "Bearer bob 5", body)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.Method == http.MethodPost {
+			w.Header().Set("Location", "/items/2")
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+	defer ts.Close()
+
+	for _, path := range []string{"/items", "/items/2"} {
+		_, _, err := cachedGet(client, Params{URL: ts.URL + path})
+		require.NoError(t, err)
+	}
+
+	err := client.Do(context.Background(), Params{URL: ts.URL + "/items", Method: http.MethodPost, Body: Input{}}, nil)
+	require.NoError(t, err)
+
+	for _, path := range []string{"/items", "/items/2"} {
+		status, _, err := cachedGet(client, Params{URL: ts.URL + path})
+		require.NoError(t, err)
+		assert.Equal(t, CacheMiss, status, path)
+	}
+}
+
+func TestCacheMaxEntryBytes(t *testing.T) {
+	client, _, clock := newTestCache(CacheSettings{MaxEntryBytes: 10})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(strings.Repeat("a", 20)))
+	})
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		status, body, err := cachedGet(client, Params{URL: ts.URL})
+		require.NoError(t, err)
+		assert.Equal(t, CacheMiss, status)
+		assert.Equal(t, strings.Repeat("a", 20), body)
+	}
+}
@@ -0,0 +1,313 @@
+package request
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heuristicFreshnessFactor is the fraction of the time since the last modification that a response
+// without explicit expiration time is considered fresh, as suggested by RFC 9111.
+const heuristicFreshnessFactor = 10
+
+// heuristicallyCacheable are the status codes that can be cached without explicit expiration time.
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// unmodifiableHeaders are not updated from the headers of a 304 response.
+var unmodifiableHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+}
+
+// cacheControl holds the directives of the Cache-Control headers with lowercase names.
+type cacheControl map[string]string
+
+// parseCacheControl parses all Cache-Control headers. Quoted values are unquoted.
+func parseCacheControl(header http.Header) cacheControl {
+	directives := cacheControl{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+		}
+	}
+
+	return directives
+}
+
+func (c cacheControl) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// duration returns the value of a directive in seconds like max-age as duration.
+func (c cacheControl) duration(name string) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(c[name], 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cacheEntry is a stored response together with the information needed to calculate its age.
+type cacheEntry struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// RequestHeader holds the values of the request headers listed in the Vary header of the response.
+	RequestHeader http.Header `json:"requestHeader"`
+	RequestTime   time.Time   `json:"requestTime"`
+	ResponseTime  time.Time   `json:"responseTime"`
+	// Credentials is the hash of the Authorization header of the request in a private cache.
+	Credentials string `json:"credentials,omitempty"`
+}
+
+func newCacheEntry(req *http.Request, res *http.Response, body []byte, requestTime time.Time, responseTime time.Time) *cacheEntry {
+	entry := &cacheEntry{
+		StatusCode:    res.StatusCode,
+		Header:        res.Header.Clone(),
+		Body:          body,
+		RequestHeader: http.Header{},
+		RequestTime:   requestTime,
+		ResponseTime:  responseTime,
+	}
+
+	for _, name := range varyHeaders(res.Header) {
+		entry.RequestHeader[name] = req.Header.Values(name)
+	}
+
+	return entry
+}
+
+// varyHeaders returns the canonical names of the headers listed in the Vary header.
+func varyHeaders(header http.Header) []string {
+	names := []string{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
+
+// matches checks if the request has the same credentials and the same values for the headers listed in the Vary header.
+func (e *cacheEntry) matches(req *http.Request, credentials string) bool {
+	if e.Credentials != credentials {
+		return false
+	}
+
+	for _, name := range varyHeaders(e.Header) {
+		if name == "*" || normalizeHeaderValues(req.Header.Values(name)) != normalizeHeaderValues(e.RequestHeader.Values(name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// variant identifies the requests the entry is served to by the credentials and the values of the Vary headers.
+func (e *cacheEntry) variant() string {
+	parts := []string{e.Credentials}
+	for _, name := range varyHeaders(e.Header) {
+		parts = append(parts, name+": "+normalizeHeaderValues(e.RequestHeader.Values(name)))
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// normalizeHeaderValues combines the values to a single list without whitespace around the elements.
+func normalizeHeaderValues(values []string) string {
+	elements := []string{}
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			elements = append(elements, strings.TrimSpace(element))
+		}
+	}
+
+	return strings.Join(elements, ",")
+}
+
+// age calculates the current age of the response as defined in RFC 9111 section 4.2.3.
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+
+	ageValue := time.Duration(0)
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// date returns the Date header or the time the response was received if it is missing.
+func (e *cacheEntry) date() time.Time {
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		return e.ResponseTime
+	}
+
+	return date
+}
+
+// lifetime returns the freshness lifetime of the response. s-maxage is only used by shared caches.
+func (e *cacheEntry) lifetime(shared bool) time.Duration {
+	control := parseCacheControl(e.Header)
+	if shared {
+		if maxAge, ok := control.duration("s-maxage"); ok {
+			return maxAge
+		}
+	}
+
+	if maxAge, ok := control.duration("max-age"); ok {
+		return maxAge
+	}
+
+	if e.Header.Get("Expires") != "" {
+		expires, err := http.ParseTime(e.Header.Get("Expires"))
+		if err != nil || expires.Before(e.date()) {
+			return 0
+		}
+		return expires.Sub(e.date())
+	}
+
+	return e.heuristicLifetime()
+}
+
+// heuristicLifetime returns a tenth of the time since the last modification for responses that are cacheable by default.
+func (e *cacheEntry) heuristicLifetime() time.Duration {
+	lastModified, err := http.ParseTime(e.Header.Get("Last-Modified"))
+	if err != nil || !heuristicallyCacheable[e.StatusCode] || lastModified.After(e.date()) {
+		return 0
+	}
+
+	return e.date().Sub(lastModified) / heuristicFreshnessFactor
+}
+
+// usable checks if the response can be served without contacting the server, taking the directives of
+// the request into account. stale is true if the response is only usable because of max-stale.
+func (e *cacheEntry) usable(now time.Time, request cacheControl, shared bool) (usable bool, stale bool) {
+	response := parseCacheControl(e.Header)
+	if request.has("no-cache") || response.has("no-cache") {
+		return false, false
+	}
+
+	age := e.age(now)
+	lifetime := e.lifetime(shared)
+	if !acceptsAge(request, age, lifetime) {
+		return false, false
+	}
+	if age < lifetime {
+		return true, false
+	}
+
+	if !request.has("max-stale") || mustRevalidate(response, shared) {
+		return false, false
+	}
+	maxStale, ok := request.duration("max-stale")
+	return !ok || age-lifetime <= maxStale, true
+}
+
+// acceptsAge checks the max-age and min-fresh directives of the request.
+func acceptsAge(request cacheControl, age time.Duration, lifetime time.Duration) bool {
+	if maxAge, ok := request.duration("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := request.duration("min-fresh"); ok && lifetime-age < minFresh {
+		return false
+	}
+
+	return true
+}
+
+// staleWithin checks if the response is stale for no longer than the duration of the directive,
+// which is taken from the response or, for stale-if-error, the request as well.
+func (e *cacheEntry) staleWithin(now time.Time, directive string, request cacheControl, shared bool) bool {
+	response := parseCacheControl(e.Header)
+	if mustRevalidate(response, shared) || response.has("no-cache") {
+		return false
+	}
+
+	limit, ok := response.duration(directive)
+	if requestLimit, requestOK := request.duration(directive); requestOK {
+		limit, ok = requestLimit, true
+	}
+
+	return ok && e.age(now)-e.lifetime(shared) <= limit
+}
+
+// mustRevalidate checks if stale responses must not be served.
+func mustRevalidate(response cacheControl, shared bool) bool {
+	return response.has("must-revalidate") || (shared && response.has("proxy-revalidate"))
+}
+
+// update replaces the headers with the ones of a 304 response and resets the age of the entry.
+func (e *cacheEntry) update(header http.Header, requestTime time.Time, responseTime time.Time) {
+	for name, values := range header {
+		if !unmodifiableHeaders[name] {
+			e.Header[name] = values
+		}
+	}
+
+	e.RequestTime = requestTime
+	e.ResponseTime = responseTime
+}
+
+// setValidators sets the conditional headers for revalidating the entry.
+func (e *cacheEntry) setValidators(req *http.Request) {
+	if etag := e.Header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// response creates a response for the request from the entry. The Age header is set to the current age.
+func (e *cacheEntry) response(req *http.Request, now time.Time, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(e.age(now)/time.Second), 10))
+	header.Set(CacheStatusHeader, status)
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,75 @@
+package request
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	header := http.Header{"Cache-Control": []string{`Max-Age=60, no-cache="Set-Cookie"`, "private,,"}}
+	control := parseCacheControl(header)
+
+	assert.Equal(t, cacheControl{"max-age": "60", "no-cache": "Set-Cookie", "private": ""}, control)
+	maxAge, ok := control.duration("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, maxAge)
+	_, ok = control.duration("private")
+	assert.False(t, ok)
+}
+
+func TestCacheEntryAge(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	entry := &cacheEntry{
+		Header:       http.Header{"Date": []string{now.Add(-20 * time.Second).Format(http.TimeFormat)}},
+		RequestTime:  now.Add(-2 * time.Second),
+		ResponseTime: now.Add(-time.Second),
+	}
+
+	// The apparent age of 19 seconds is larger than the corrected age value of 1 second.
+	assert.Equal(t, 20*time.Second, entry.age(now))
+
+	entry.Header.Set("Age", "30")
+	assert.Equal(t, 32*time.Second, entry.age(now))
+}
+
+func TestCacheEntryLifetime(t *testing.T) {
+	date := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		header   http.Header
+		shared   bool
+		expected time.Duration
+	}{
+		"s-maxage":                 {http.Header{"Cache-Control": []string{"max-age=10, s-maxage=20"}}, true, 20 * time.Second},
+		"private ignores s-maxage": {http.Header{"Cache-Control": []string{"max-age=10, s-maxage=20"}}, false, 10 * time.Second},
+		"expires":                  {http.Header{"Expires": []string{date.Add(time.Hour).Format(http.TimeFormat)}}, false, time.Hour},
+		"invalid expires":          {http.Header{"Expires": []string{"0"}}, false, 0},
+		"heuristic":                {http.Header{"Last-Modified": []string{date.Add(-time.Hour).Format(http.TimeFormat)}}, false, 6 * time.Minute},
+		"none":                     {http.Header{}, false, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.header.Set("Date", date.Format(http.TimeFormat))
+			entry := &cacheEntry{StatusCode: http.StatusOK, Header: test.header}
+			assert.Equal(t, test.expected, entry.lifetime(test.shared))
+		})
+	}
+}
+
+func TestCacheEntryMatches(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	req.Header.Set("Accept", "text/html, application/json")
+	res := &http.Response{Header: http.Header{"Vary": []string{"accept, Accept-Language"}}}
+	entry := newCacheEntry(req, res, nil, time.Now(), time.Now())
+
+	other, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	other.Header.Set("Accept", "text/html,application/json")
+	assert.True(t, entry.matches(other, ""))
+	assert.False(t, entry.matches(other, "credentials"))
+
+	other.Header.Set("Accept-Language", "de")
+	assert.False(t, entry.matches(other, ""))
+}
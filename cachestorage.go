@@ -0,0 +1,165 @@
+package request
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStorage stores the serialized responses of a Cache. Implementations must be safe for concurrent use.
+// Storages that can fail, e.g. because of network or file system errors, should treat errors as cache misses.
+type CacheStorage interface {
+	// Get returns the value stored for the key and whether it was found.
+	Get(key string) ([]byte, bool)
+	// Set stores the value for the key.
+	Set(key string, value []byte)
+	// Delete removes the value of the key.
+	Delete(key string)
+}
+
+// MemoryCache is a CacheStorage that keeps the responses in memory. Once the size of the stored responses
+// exceeds the limit, the least recently used ones are removed.
+type MemoryCache struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	size     int64
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache returns a memory cache that holds up to maxBytes of keys and values.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns the value stored for the key and marks it as recently used.
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	m.order.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).value, true
+}
+
+// Set stores the value for the key. Values that are larger than the limit of the cache are not stored.
+func (m *MemoryCache) Set(key string, value []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.remove(key)
+	size := int64(len(key) + len(value))
+	if size > m.maxBytes {
+		return
+	}
+
+	m.items[key] = m.order.PushFront(&memoryCacheItem{key: key, value: value})
+	m.size += size
+	for m.size > m.maxBytes {
+		m.remove(m.order.Back().Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete removes the value of the key.
+func (m *MemoryCache) Delete(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.remove(key)
+}
+
+// Len returns the number of stored values.
+func (m *MemoryCache) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.order.Len()
+}
+
+// remove deletes the item of the key. The mutex must be held by the caller.
+func (m *MemoryCache) remove(key string) {
+	element, ok := m.items[key]
+	if !ok {
+		return
+	}
+
+	item := element.Value.(*memoryCacheItem)
+	m.order.Remove(element)
+	delete(m.items, key)
+	m.size -= int64(len(item.key) + len(item.value))
+}
+
+// DiskCache is a CacheStorage that keeps every response in a file of the directory.
+// The size of the directory is not limited. Errors of the file system are treated as cache misses.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache returns a disk cache that stores the responses in the directory.
+// The directory is created on first use.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// Get returns the content of the file of the key.
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	value, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set writes the value to the file of the key. The file is replaced atomically,
+// so concurrent readers never see a partially written value.
+func (d *DiskCache) Set(key string, value []byte) {
+	err := os.MkdirAll(d.dir, 0o700)
+	if err != nil {
+		return
+	}
+
+	file, err := ioutil.TempFile(d.dir, "tmp-")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(value)
+	if cErr := file.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+}
+
+// Delete removes the file of the key.
+func (d *DiskCache) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
+
+// path returns the file name for the key, which is the hex encoded SHA-256 hash of the key.
+func (d *DiskCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(hash[:]))
+}
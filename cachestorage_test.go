@@ -0,0 +1,88 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(20)
+	cache.Set("a", []byte("123456"))
+	cache.Set("b", []byte("123456"))
+
+	value, ok := cache.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, []byte("123456"), value)
+
+	// "b" is the least recently used entry now, so it is removed first.
+	cache.Set("c", []byte("123456"))
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Set("a", []byte("1"))
+	value, _ = cache.Get("a")
+	assert.Equal(t, []byte("1"), value)
+
+	cache.Set("too large", []byte("12345678901234567890"))
+	_, ok = cache.Get("too large")
+	assert.False(t, ok)
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache := NewDiskCache(dir)
+
+	_, ok := cache.Get("https://example.com/items")
+	assert.False(t, ok)
+
+	cache.Set("https://example.com/items", []byte("value"))
+	value, ok := cache.Get("https://example.com/items")
+	require.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	cache.Set("https://example.com/items", []byte("new value"))
+	value, _ = cache.Get("https://example.com/items")
+	assert.Equal(t, []byte("new value"), value)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	cache.Delete("https://example.com/items")
+	_, ok = cache.Get("https://example.com/items")
+	assert.False(t, ok)
+}
+
+func TestCacheWithDiskStorage(t *testing.T) {
+	storage := NewDiskCache(t.TempDir())
+	client, _, clock := newTestCache(CacheSettings{Storage: storage})
+	calls := int32(0)
+	ts := newCacheServer(clock, &calls, func(w http.ResponseWriter, r *http.Request, call int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("content"))
+	})
+	defer ts.Close()
+
+	_, _, err := cachedGet(client, Params{URL: ts.URL})
+	require.NoError(t, err)
+
+	// A new cache with the same directory serves the stored response.
+	other, _, _ := newTestCache(CacheSettings{Storage: NewDiskCache(storage.dir)})
+	status, body, err := cachedGet(other, Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, status)
+	assert.Equal(t, "content", body)
+}
@@ -0,0 +1,438 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client holds the configuration that is applied to all requests made with it.
+// The fields should not be changed once the client is in use, after that the
+// client is safe for concurrent use by multiple goroutines.
+type Client struct {
+	// HTTPClient is used to send the requests. If it is nil, the http client
+	// of the default client is used or, if that is nil as well, one that does
+	// not follow redirects and uses the shared transport.
+	HTTPClient *http.Client
+
+	// BaseURL is prepended to the URL of all requests that do not use an absolute URL.
+	BaseURL string
+
+	// Headers are set on every request. Headers in the request params take precedence.
+	Headers map[string]string
+
+	// Timeout is applied to all requests that do not specify their own timeout.
+	// It is enforced via the request context, a timeout of the http client still applies.
+	Timeout time.Duration
+
+	// Codec is used to encode the request body and to decode response bodies with a content type
+	// that has no registered codec. If it is nil, JSON is used.
+	Codec Codec
+
+	// Retry is the retry policy for all requests that do not specify their own.
+	// If it is nil, requests are only attempted once.
+	Retry *RetryPolicy
+
+	// RateLimitTracker keeps the rate limits announced by the servers. If it is set,
+	// requests to a rate limited host are delayed until the limit is reset.
+	RateLimitTracker *RateLimitTracker
+
+	// CircuitBreaker rejects requests with ErrCircuitOpen while the dependency is failing.
+	CircuitBreaker *CircuitBreaker
+
+	// RateLimiter limits the number of requests for all requests that do not specify their own limiter.
+	RateLimiter *RateLimiter
+
+	// Middlewares wrap every attempt of a request. They run before the middlewares of the request params.
+	Middlewares []Middleware
+
+	// MaxResponseBytes limits the size of the response bodies of all requests that do not specify their own limit.
+	// Reading a larger body fails with a ResponseTooLargeError. If it is zero, the size is not limited.
+	MaxResponseBytes int64
+
+	// MaxErrorBodyBytes limits how much of an error response body is read into an Error for all requests
+	// that do not specify their own limit. Larger bodies are truncated. It defaults to 64 KiB.
+	MaxErrorBodyBytes int64
+
+	// Compression compresses the request bodies of all requests that do not specify their own compression.
+	Compression *Compression
+
+	// ErrorBodies are the types the bodies of error responses are decoded into for all requests
+	// that do not specify their own. The decoded body is available in the returned Error.
+	ErrorBodies ErrorBodies
+}
+
+// NewClient returns a client that does not follow redirects and has a timeout of defaultTimeout.
+// The client uses the transport that is shared by all clients created by the package.
+func NewClient() *Client {
+	return NewClientWithTransport(sharedTransport)
+}
+
+// NewClientWithTransport is the same as NewClient but the client uses the provided transport.
+// Use NewTransport to create a transport with custom connection pool settings.
+func NewClientWithTransport(transport http.RoundTripper) *Client {
+	return &Client{
+		HTTPClient: &http.Client{
+			CheckRedirect: noRedirect,
+			Transport:     transport,
+		},
+		Timeout: defaultTimeout,
+		Codec:   JSONCodec{},
+	}
+}
+
+var (
+	defaultClient      *Client
+	defaultClientMutex sync.RWMutex
+)
+
+// defaultHTTPClient is used if neither the client nor the default client have an http client.
+var defaultHTTPClient = &http.Client{
+	CheckRedirect: noRedirect,
+	Transport:     sharedTransport,
+}
+
+// getDefaultClient returns the client that is used by the package level functions.
+// It is created on first use.
+func getDefaultClient() *Client {
+	defaultClientMutex.RLock()
+	client := defaultClient
+	defaultClientMutex.RUnlock()
+	if client != nil {
+		return client
+	}
+
+	defaultClientMutex.Lock()
+	defer defaultClientMutex.Unlock()
+	if defaultClient == nil {
+		defaultClient = NewClient()
+	}
+
+	return defaultClient
+}
+
+// SetDefaultClient replaces the client that is used by the package level functions.
+func SetDefaultClient(client *Client) {
+	defaultClientMutex.Lock()
+	defer defaultClientMutex.Unlock()
+	defaultClient = client
+}
+
+// Do executes the request as specified in the request params.
+// The response body will be parsed into the provided struct.
+// Optionally, the headers will be copied if a header map was provided.
+func (c *Client) Do(ctx context.Context, params Params, responseBody interface{}, responseHeaderArg ...http.Header) error {
+	res, err := c.do(ctx, params, responseBody)
+	if err != nil {
+		return err
+	}
+
+	return populateResponseHeader(res.Header, responseHeaderArg)
+}
+
+// DoResponse is the same as Do but returns the metadata of the response.
+// If RetainResponseBody is set in the params, the response contains the raw body as well.
+func (c *Client) DoResponse(ctx context.Context, params Params, responseBody interface{}) (*Response, error) {
+	return c.do(ctx, params, responseBody)
+}
+
+// DoWithStringResponse is the same as Do but the response body is returned as string
+// instead of being parsed into the provided struct.
+func (c *Client) DoWithStringResponse(ctx context.Context, params Params) (string, error) {
+	params.RetainResponseBody = true
+	res, err := c.do(ctx, params, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.RawBody), nil
+}
+
+// do executes the request and decodes the response body into the provided value if it is not nil.
+// If decoding fails, the response is returned together with the error.
+func (c *Client) do(ctx context.Context, params Params, responseBody interface{}) (*Response, error) {
+	return c.receive(ctx, params, func(result *Response, body io.Reader) error {
+		if params.RetainResponseBody {
+			rawBody, err := ioutil.ReadAll(body)
+			if err != nil {
+				return fmt.Errorf("failed to read response body: %w", err)
+			}
+			result.RawBody = rawBody
+			body = bytes.NewReader(rawBody)
+		}
+
+		var decodeErr error
+		if responseBody != nil {
+			decodeErr = c.responseCodec(params, result.Header.Get("Content-Type")).Decode(body, responseBody)
+		}
+
+		// The trailers are only available once the body was read completely. Without announced trailers
+		// the rest of the body is not read, so a server that keeps the stream open does not block the call.
+		if len(result.Trailer) > 0 {
+			_, _ = io.Copy(ioutil.Discard, body)
+		}
+		return decodeErr
+	})
+}
+
+// Get is a convenience wrapper for "Do" to execute GET requests
+func (c *Client) Get(ctx context.Context, url string, responseBody interface{}) error {
+	return c.Do(ctx, Params{Method: http.MethodGet, URL: url}, responseBody)
+}
+
+// Post is a convenience wrapper for "Do" to execute POST requests
+func (c *Client) Post(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
+	return c.Do(ctx, Params{Method: http.MethodPost, URL: url, Body: requestBody}, responseBody)
+}
+
+// Put is a convenience wrapper for "Do" to execute PUT requests
+func (c *Client) Put(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
+	return c.Do(ctx, Params{Method: http.MethodPut, URL: url, Body: requestBody}, responseBody)
+}
+
+// Patch is a convenience wrapper for "Do" to execute PATCH requests
+func (c *Client) Patch(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
+	return c.Do(ctx, Params{Method: http.MethodPatch, URL: url, Body: requestBody}, responseBody)
+}
+
+// Delete is a convenience wrapper for "Do" to execute DELETE requests
+func (c *Client) Delete(ctx context.Context, url string, responseBody interface{}) error {
+	return c.Do(ctx, Params{Method: http.MethodDelete, URL: url}, responseBody)
+}
+
+// send executes the request including retries and checks the response code.
+// If no error is returned, the caller is responsible for closing the response body.
+func (c *Client) send(ctx context.Context, params Params) (res *http.Response, returnErr error) {
+	body, err := prepareBody(params, c.codec(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	defer func() {
+		if cErr := body.close(); cErr != nil && returnErr == nil {
+			returnErr = cErr
+		}
+	}()
+
+	err = body.compress(c.compression(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+
+	start := time.Now()
+	policy := c.retryPolicy(params)
+	if policy == nil {
+		res, err = c.sendOnce(ctx, params, body)
+	} else {
+		res, err = c.sendWithRetries(ctx, params, body, policy)
+	}
+
+	if responseErr := asError(err); responseErr != nil {
+		responseErr.Duration = time.Since(start)
+	}
+
+	return res, err
+}
+
+// sendOnce makes a single attempt to execute the request and checks the response code.
+func (c *Client) sendOnce(ctx context.Context, params Params, body *requestBody) (*http.Response, error) {
+	req, err := c.createRequest(ctx, params, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	res, err := c.handler(params)(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkResponseCode(res, params.ExpectedResponseCode, c.maxErrorBodyBytes(params))
+	if err != nil {
+		_ = res.Body.Close()
+		c.decodeErrorBody(params, err)
+		switch {
+		case isRateLimitStatus(res.StatusCode):
+			return nil, &RateLimitError{Err: err, RateLimit: parseRateLimit(res.Header, time.Now())}
+		case res.StatusCode == http.StatusPreconditionFailed:
+			return nil, &PreconditionFailedError{Err: err}
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// roundTrip sends the request while respecting the rate limits and the circuit breaker.
+func (c *Client) roundTrip(req *http.Request, params Params) (*http.Response, error) {
+	done, err := c.beforeSend(req, params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient().Do(req)
+	done(res, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	decompress(res)
+	return res, nil
+}
+
+// beforeSend waits for the rate limits and checks the circuit breaker.
+// The returned function must be called with the result of the request.
+func (c *Client) beforeSend(req *http.Request, params Params) (func(res *http.Response, err error), error) {
+	host := req.URL.Host
+	if limiter := c.rateLimiter(params); limiter != nil {
+		err := limiter.Wait(req.Context(), keyOrDefault(params.RateLimitKey, host))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.RateLimitTracker != nil {
+		err := c.RateLimitTracker.wait(req.Context(), host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	breakerDone := func(res *http.Response, err error) {}
+	if c.CircuitBreaker != nil {
+		var err error
+		breakerDone, err = c.CircuitBreaker.allow(keyOrDefault(params.CircuitBreakerKey, host))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(res *http.Response, err error) {
+		breakerDone(res, err)
+		if c.RateLimitTracker != nil && err == nil {
+			now := time.Now()
+			c.RateLimitTracker.record(host, res.StatusCode, parseRateLimit(res.Header, now), now)
+		}
+	}, nil
+}
+
+// keyOrDefault returns the key if it is set and the default key otherwise.
+func keyOrDefault(key string, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+
+	return key
+}
+
+func (c *Client) createRequest(ctx context.Context, params Params, body *requestBody) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, params.Method, c.resolveURL(params.URL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	err = body.attach(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setHeaders(req, params, body)
+	if len(params.Query) > 0 {
+		q := req.URL.Query()
+		for key, value := range params.Query {
+			q.Add(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	return req, nil
+}
+
+// setHeaders sets the default headers followed by the headers of the client and the params.
+func (c *Client) setHeaders(req *http.Request, params Params, body *requestBody) {
+	codec := c.codec(params)
+	req.Header.Set("Accept", codec.ContentType()+", "+ProblemContentType)
+	req.Header.Set("Accept-Encoding", acceptEncoding())
+	req.Header.Set("Content-Type", codec.ContentType())
+	if body.contentType != "" {
+		req.Header.Set("Content-Type", body.contentType)
+	}
+	if body.contentEncoding != "" {
+		req.Header.Set("Content-Encoding", body.contentEncoding)
+	}
+
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range params.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// resolveURL prepends the base URL if the request URL is not absolute.
+func (c *Client) resolveURL(rawURL string) string {
+	if c.BaseURL == "" {
+		return rawURL
+	}
+
+	if u, err := url.Parse(rawURL); err == nil && u.IsAbs() {
+		return rawURL
+	}
+
+	if rawURL == "" {
+		return c.BaseURL
+	}
+
+	return strings.TrimRight(c.BaseURL, "/") + "/" + strings.TrimLeft(rawURL, "/")
+}
+
+// withTimeout applies the timeout of the request params to the context, the earlier of it and the deadline
+// of the context is used. If the params have no timeout, the one of the client is applied unless the context
+// already has a deadline.
+func (c *Client) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		if _, ok := ctx.Deadline(); ok {
+			return ctx, func() {}
+		}
+		timeout = c.Timeout
+	}
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	if httpClient := getDefaultClient().HTTPClient; httpClient != nil {
+		return httpClient
+	}
+
+	return defaultHTTPClient
+}
+
+func (c *Client) retryPolicy(params Params) *RetryPolicy {
+	if params.Retry != nil {
+		return params.Retry
+	}
+
+	return c.Retry
+}
+
+func (c *Client) rateLimiter(params Params) *RateLimiter {
+	if params.RateLimiter != nil {
+		return params.RateLimiter
+	}
+
+	return c.RateLimiter
+}
@@ -0,0 +1,161 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	client := NewClient()
+	assert.Equal(t, defaultTimeout, client.Timeout)
+	assert.Equal(t, JSONCodec{}, client.Codec)
+	assert.NotNil(t, client.HTTPClient)
+	assert.True(t, client.HTTPClient != NewClient().HTTPClient)
+}
+
+func TestGetDefaultClientConcurrently(t *testing.T) {
+	wg := sync.WaitGroup{}
+	clients := make([]*Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i] = getDefaultClient()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, client := range clients {
+		assert.True(t, client == clients[0])
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	t.Run("base url and default headers", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/users", r.URL.Path)
+			assert.Equal(t, "clientValue", r.Header.Get("Client-Header"))
+			assert.Equal(t, "paramsValue", r.Header.Get("Overwritten-Header"))
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		client := NewClient()
+		client.BaseURL = ts.URL + "/api/"
+		client.Headers = map[string]string{
+			"Client-Header":      "clientValue",
+			"Overwritten-Header": "clientValue",
+		}
+
+		params := Params{
+			URL:     "/users",
+			Headers: map[string]string{"Overwritten-Header": "paramsValue"},
+		}
+
+		result := &Output{}
+		err := client.Do(context.Background(), params, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("absolute url ignores base url", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/other", r.URL.Path)
+		}))
+		defer ts.Close()
+
+		client := NewClient()
+		client.BaseURL = "http://example.invalid/api"
+
+		err := client.Get(context.Background(), ts.URL+"/other", nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("client timeout", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		client := NewClient()
+		client.Timeout = time.Millisecond
+
+		err := client.Get(context.Background(), ts.URL, nil)
+		assert.Error(t, err)
+
+		// The timeout of the params takes priority.
+		err = client.Do(context.Background(), Params{URL: ts.URL, Timeout: time.Second}, nil)
+		assert.NoError(t, err)
+
+		// Other clients are not affected.
+		err = NewClient().Get(context.Background(), ts.URL, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("zero value client", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json, application/problem+json", r.Header.Get("Accept"))
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		client := &Client{}
+		result := &Output{}
+		err := client.Get(context.Background(), ts.URL, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("string response", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`some response`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		result, err := NewClient().DoWithStringResponse(context.Background(), Params{URL: ts.URL})
+		assert.NoError(t, err)
+		assert.Equal(t, "some response", result)
+	})
+}
+
+func TestClientConvenienceWrappers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPut || r.Method == http.MethodPatch || r.Method == http.MethodPost {
+			assert.Equal(t, `{"requestValue":"someValueIn"}`+"\n", string(body))
+		}
+		_, err := w.Write([]byte(`{"responseValue":"` + r.Method + `"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient()
+	ctx := context.Background()
+	input := Input{RequestValue: "someValueIn"}
+
+	result := &Output{}
+	assert.NoError(t, client.Get(ctx, ts.URL, result))
+	assert.Equal(t, "GET", result.ResponseValue)
+
+	assert.NoError(t, client.Delete(ctx, ts.URL, result))
+	assert.Equal(t, "DELETE", result.ResponseValue)
+
+	assert.NoError(t, client.Put(ctx, ts.URL, input, result))
+	assert.Equal(t, "PUT", result.ResponseValue)
+
+	assert.NoError(t, client.Patch(ctx, ts.URL, input, result))
+	assert.Equal(t, "PATCH", result.ResponseValue)
+
+	assert.NoError(t, client.Post(ctx, ts.URL, input, result))
+	assert.Equal(t, "POST", result.ResponseValue)
+}
@@ -0,0 +1,174 @@
+package request
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Codec encodes request bodies and decodes response bodies.
+type Codec interface {
+	// ContentType returns the media type used for the Accept and Content-Type headers.
+	ContentType() string
+	// Encode writes the encoded value to the writer.
+	Encode(w io.Writer, v interface{}) error
+	// Decode reads from the reader and decodes the result into the value.
+	Decode(r io.Reader, v interface{}) error
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{
+		"application/json":                  JSONCodec{},
+		"application/xml":                   XMLCodec{},
+		"text/xml":                          XMLCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+	}
+)
+
+// RegisterCodec registers the codec for the media type. Response bodies with that content type
+// are decoded with the codec. Registering a codec for a media type replaces the previous one.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[strings.ToLower(mediaType)] = codec
+}
+
+// CodecFor returns the codec registered for the media type of the content type.
+// Media types with a structured syntax suffix like "application/vnd.api+json" fall back
+// to the codec registered for the suffix, e.g. "application/json".
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		codec, ok := codecs["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+
+	return nil, false
+}
+
+// JSONCodec encodes and decodes JSON using the encoding/json package.
+type JSONCodec struct{}
+
+// ContentType returns "application/json".
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Encode writes the JSON encoding of v to w.
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode reads JSON from r and stores the result in v.
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLCodec encodes and decodes XML using the encoding/xml package.
+type XMLCodec struct{}
+
+// ContentType returns "application/xml".
+func (XMLCodec) ContentType() string {
+	return "application/xml"
+}
+
+// Encode writes the XML encoding of v to w.
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// Decode reads XML from r and stores the result in v.
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// FormCodec encodes and decodes application/x-www-form-urlencoded bodies.
+// Supported values are url.Values, maps with string keys and structs, see Form for details.
+// Decoding is supported for url.Values, map[string]string and map[string][]string.
+type FormCodec struct{}
+
+// ContentType returns "application/x-www-form-urlencoded".
+func (FormCodec) ContentType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+// Encode writes the form encoding of v to w.
+func (FormCodec) Encode(w io.Writer, v interface{}) error {
+	values, err := formValues(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, values.Encode())
+	return err
+}
+
+// Decode reads a form from r and stores the result in v.
+func (FormCodec) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+	case *map[string][]string:
+		*target = values
+	case *map[string]string:
+		*target = map[string]string{}
+		for key := range values {
+			(*target)[key] = values.Get(key)
+		}
+	default:
+		return fmt.Errorf("cannot decode form into %T", v)
+	}
+
+	return nil
+}
+
+// codec returns the codec for the request body. The codec of the params takes precedence
+// over the one of the client, if none is set JSON is used.
+func (c *Client) codec(params Params) Codec {
+	if params.Codec != nil {
+		return params.Codec
+	}
+
+	if c.Codec != nil {
+		return c.Codec
+	}
+
+	return JSONCodec{}
+}
+
+// responseCodec returns the codec registered for the content type of the response.
+// If there is none, the codec of the request is used.
+func (c *Client) responseCodec(params Params, contentType string) Codec {
+	if codec, ok := CodecFor(contentType); ok {
+		return codec
+	}
+
+	return c.codec(params)
+}
@@ -0,0 +1,176 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type xmlInput struct {
+	XMLName      xml.Name `xml:"input"`
+	RequestValue string   `xml:"requestValue"`
+}
+
+type xmlOutput struct {
+	XMLName       xml.Name `xml:"output"`
+	ResponseValue string   `xml:"responseValue"`
+}
+
+func TestCodecFor(t *testing.T) {
+	tests := map[string]Codec{
+		"application/json":                  JSONCodec{},
+		"application/json; charset=utf-8":   JSONCodec{},
+		"application/vnd.api+json":          JSONCodec{},
+		"application/problem+json":          JSONCodec{},
+		"text/xml; charset=utf-8":           XMLCodec{},
+		"application/atom+xml":              XMLCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+	}
+	for contentType, expected := range tests {
+		codec, ok := CodecFor(contentType)
+		assert.True(t, ok, contentType)
+		assert.Equal(t, expected, codec, contentType)
+	}
+
+	for _, contentType := range []string{"", "text/plain", "application/vnd.custom+yaml", "invalid/"} {
+		_, ok := CodecFor(contentType)
+		assert.False(t, ok, contentType)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec("application/X-Custom", XMLCodec{})
+	defer func() {
+		codecsMutex.Lock()
+		delete(codecs, "application/x-custom")
+		codecsMutex.Unlock()
+	}()
+
+	codec, ok := CodecFor("application/x-custom")
+	assert.True(t, ok)
+	assert.Equal(t, XMLCodec{}, codec)
+}
+
+func TestXMLCodec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/xml", r.Header.Get("Content-Type"))
+		assert.Equal(t, "application/xml, application/problem+json", r.Header.Get("Accept"))
+		input := &xmlInput{}
+		assert.NoError(t, xml.NewDecoder(r.Body).Decode(input))
+		assert.Equal(t, "someValueIn", input.RequestValue)
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, err := w.Write([]byte(`<output><responseValue>someValueOut</responseValue></output>`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	t.Run("codec of the params", func(t *testing.T) {
+		result := &xmlOutput{}
+		err := Do(Params{URL: ts.URL, Method: http.MethodPost, Body: xmlInput{RequestValue: "someValueIn"}, Codec: XMLCodec{}}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("codec of the client", func(t *testing.T) {
+		client := NewClient()
+		client.Codec = XMLCodec{}
+		result := &xmlOutput{}
+		err := client.Post(context.Background(), ts.URL, xmlInput{RequestValue: "someValueIn"}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+}
+
+func TestResponseCodec(t *testing.T) {
+	t.Run("decoder is chosen by the content type", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			_, err := w.Write([]byte(`<output><responseValue>someValueOut</responseValue></output>`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		result := &xmlOutput{}
+		err := Get(ts.URL, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("unknown content type uses the request codec", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		result := &Output{}
+		err := Get(ts.URL, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+}
+
+func TestFormCodec(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		for _, value := range []interface{}{
+			url.Values{"a": {"1", "2"}, "b": {"x y"}},
+			map[string][]string{"a": {"1", "2"}, "b": {"x y"}},
+		} {
+			buffer := &bytes.Buffer{}
+			require.NoError(t, FormCodec{}.Encode(buffer, value))
+			assert.Equal(t, "a=1&a=2&b=x+y", buffer.String())
+		}
+
+		buffer := &bytes.Buffer{}
+		require.NoError(t, FormCodec{}.Encode(buffer, map[string]string{"a": "1"}))
+		assert.Equal(t, "a=1", buffer.String())
+
+		assert.Error(t, FormCodec{}.Encode(buffer, 42))
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		values := url.Values{}
+		require.NoError(t, FormCodec{}.Decode(strings.NewReader("a=1&a=2"), &values))
+		assert.Equal(t, url.Values{"a": {"1", "2"}}, values)
+
+		multi := map[string][]string{}
+		require.NoError(t, FormCodec{}.Decode(strings.NewReader("a=1&a=2"), &multi))
+		assert.Equal(t, map[string][]string{"a": {"1", "2"}}, multi)
+
+		single := map[string]string{}
+		require.NoError(t, FormCodec{}.Decode(strings.NewReader("a=1&b=x+y"), &single))
+		assert.Equal(t, map[string]string{"a": "1", "b": "x y"}, single)
+
+		assert.Error(t, FormCodec{}.Decode(strings.NewReader("a=1"), &Output{}))
+		assert.Error(t, FormCodec{}.Decode(strings.NewReader("a=%zz"), &values))
+	})
+
+	t.Run("request", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "grant_type=client_credentials", string(body))
+			w.Header().Set("Content-Type", "application/json")
+			_, err = w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		result := &Output{}
+		err := Do(Params{URL: ts.URL, Method: http.MethodPost, Body: map[string]string{"grant_type": "client_credentials"}, Codec: FormCodec{}}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+}
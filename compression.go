@@ -0,0 +1,198 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContentEncoding compresses and decompresses bodies for a Content-Encoding like gzip.
+type ContentEncoding interface {
+	// NewReader returns a reader that decompresses the data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	// NewWriter returns a writer that compresses the data and writes it to w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+var (
+	encodingsMutex sync.RWMutex
+	encodings      = map[string]ContentEncoding{
+		"gzip":    GzipEncoding{},
+		"deflate": DeflateEncoding{},
+	}
+)
+
+// RegisterContentEncoding registers the encoding for the name used in the Content-Encoding header, e.g. "br".
+// Responses with that encoding are decompressed and the name is added to the Accept-Encoding header of all requests.
+func RegisterContentEncoding(name string, encoding ContentEncoding) {
+	encodingsMutex.Lock()
+	defer encodingsMutex.Unlock()
+	encodings[strings.ToLower(name)] = encoding
+}
+
+func contentEncoding(name string) (ContentEncoding, bool) {
+	encodingsMutex.RLock()
+	defer encodingsMutex.RUnlock()
+	encoding, ok := encodings[strings.ToLower(strings.TrimSpace(name))]
+	return encoding, ok
+}
+
+// acceptEncoding returns the names of all registered encodings for the Accept-Encoding header.
+func acceptEncoding() string {
+	encodingsMutex.RLock()
+	defer encodingsMutex.RUnlock()
+	names := make([]string, 0, len(encodings))
+	for name := range encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// GzipEncoding implements the gzip content encoding.
+type GzipEncoding struct{}
+
+// NewReader returns a gzip reader.
+func (GzipEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// NewWriter returns a gzip writer.
+func (GzipEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// DeflateEncoding implements the deflate content encoding, which is the zlib format.
+// As some servers send raw deflate data instead, that is accepted when reading as well.
+type DeflateEncoding struct{}
+
+// NewReader returns a zlib reader or a flate reader if the data has no zlib header.
+func (DeflateEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if isZlibHeader(header) {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
+
+// NewWriter returns a zlib writer.
+func (DeflateEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+// isZlibHeader checks the compression method and the checksum of a zlib header as defined in RFC 1950.
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// Compression configures the compression of request bodies.
+type Compression struct {
+	// Encoding is the name of the content encoding, e.g. "gzip" or "deflate".
+	Encoding string
+	// MinBytes is the size a body must have to be compressed. Smaller bodies are sent as they are.
+	MinBytes int64
+}
+
+// compress compresses the body with the encoding if it is held in memory and large enough.
+func (b *requestBody) compress(compression *Compression) error {
+	if compression == nil || b.data == nil || b.size < compression.MinBytes {
+		return nil
+	}
+
+	encoding, ok := contentEncoding(compression.Encoding)
+	if !ok {
+		return fmt.Errorf("unknown content encoding %q", compression.Encoding)
+	}
+
+	buffer := &bytes.Buffer{}
+	writer, err := encoding.NewWriter(buffer)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(b.data)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	b.data = buffer.Bytes()
+	b.size = int64(buffer.Len())
+	b.contentEncoding = strings.ToLower(compression.Encoding)
+	return nil
+}
+
+func (c *Client) compression(params Params) *Compression {
+	if params.Compression != nil {
+		return params.Compression
+	}
+
+	return c.Compression
+}
+
+// decompress replaces the body of the response with a decompressing reader if the content encoding is registered.
+func decompress(res *http.Response) {
+	encoding, ok := contentEncoding(res.Header.Get("Content-Encoding"))
+	if !ok {
+		return
+	}
+
+	res.Body = &decompressingBody{body: res.Body, encoding: encoding}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+}
+
+// decompressingBody creates the decompressing reader on the first read, so empty bodies
+// like the ones of HEAD requests do not lead to an error.
+type decompressingBody struct {
+	body     io.ReadCloser
+	encoding ContentEncoding
+	reader   io.ReadCloser
+	err      error
+}
+
+func (b *decompressingBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		reader, err := b.encoding.NewReader(b.body)
+		if err != nil {
+			b.err = fmt.Errorf("failed to decompress response body: %w", err)
+		} else {
+			b.reader = reader
+		}
+	}
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.reader.Read(p)
+}
+
+func (b *decompressingBody) Close() error {
+	if b.reader != nil {
+		_ = b.reader.Close()
+	}
+
+	return b.body.Close()
+}
@@ -0,0 +1,197 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseEncoding is a test encoding that reverses the bytes of the body.
+type reverseEncoding struct{}
+
+func (reverseEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	data, err := ioutil.ReadAll(r)
+	return ioutil.NopCloser(bytes.NewReader(reverse(data))), err
+}
+
+func (reverseEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &reverseWriter{writer: w}, nil
+}
+
+type reverseWriter struct {
+	writer io.Writer
+	data   []byte
+}
+
+func (w *reverseWriter) Write(p []byte) (int, error) {
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+func (w *reverseWriter) Close() error {
+	_, err := w.writer.Write(reverse(w.data))
+	return err
+}
+
+func reverse(data []byte) []byte {
+	result := make([]byte, len(data))
+	for i, b := range data {
+		result[len(data)-1-i] = b
+	}
+	return result
+}
+
+func compressed(t *testing.T, encoding string, data string) []byte {
+	buffer := &bytes.Buffer{}
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(buffer)
+	case "deflate":
+		writer = zlib.NewWriter(buffer)
+	case "raw-deflate":
+		var err error
+		writer, err = flate.NewWriter(buffer, flate.DefaultCompression)
+		require.NoError(t, err)
+	}
+	_, err := writer.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestResponseDecompression(t *testing.T) {
+	body := `{"responseValue":"someValueOut"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "deflate, gzip", r.Header.Get("Accept-Encoding"))
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Type", "application/json")
+		switch encoding {
+		case "raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+		case "broken":
+			w.Header().Set("Content-Encoding", "gzip")
+		default:
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		if encoding == "broken" {
+			_, _ = w.Write([]byte("not compressed"))
+			return
+		}
+		if r.URL.Query().Get("status") == "error" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = w.Write(compressed(t, encoding, body))
+	}))
+	defer ts.Close()
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			result := &Output{}
+			res, err := DoResponse(context.Background(), Params{URL: ts.URL, Query: map[string]string{"encoding": encoding}}, result)
+			require.NoError(t, err)
+			assert.Equal(t, "someValueOut", result.ResponseValue)
+			assert.Empty(t, res.Header.Get("Content-Encoding"))
+		})
+	}
+
+	t.Run("error body", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL, Query: map[string]string{"encoding": "gzip", "status": "error"}}, nil)
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, body, string(responseErr.Body))
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL, Method: http.MethodHead, Query: map[string]string{"encoding": "gzip"}}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL, Query: map[string]string{"encoding": "broken"}}, &Output{})
+		assert.Contains(t, err.Error(), "failed to decompress response body")
+	})
+
+	t.Run("custom accept encoding", func(t *testing.T) {
+		result := &Output{}
+		err := NewClient().Do(context.Background(), Params{
+			URL:     ts.URL,
+			Query:   map[string]string{"encoding": "gzip"},
+			Headers: map[string]string{"Accept-Encoding": "deflate, gzip"},
+		}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+}
+
+func TestRequestCompression(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		reader := io.Reader(r.Body)
+		if decoder, ok := contentEncoding(encoding); ok {
+			reader = &decompressingBody{body: r.Body, encoding: decoder}
+		}
+		data, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Output{ResponseValue: encoding + ":" + strings.TrimSpace(string(data))})
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+	input := Input{RequestValue: strings.Repeat("a", 100)}
+	expected := `{"requestValue":"` + input.RequestValue + `"}`
+
+	t.Run("params", func(t *testing.T) {
+		for _, encoding := range []string{"gzip", "deflate"} {
+			result := &Output{}
+			err := Do(Params{URL: ts.URL, Method: http.MethodPost, Body: input, Compression: &Compression{Encoding: encoding}}, result)
+			require.NoError(t, err)
+			assert.Equal(t, encoding+":"+expected, result.ResponseValue)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		client := NewClient()
+		client.Compression = &Compression{Encoding: "gzip", MinBytes: 1000}
+		result := &Output{}
+		err := client.Post(ctx, ts.URL, input, result)
+		require.NoError(t, err)
+		assert.Equal(t, ":"+expected, result.ResponseValue)
+	})
+
+	t.Run("registered encoding", func(t *testing.T) {
+		RegisterContentEncoding("reverse", reverseEncoding{})
+		defer func() {
+			encodingsMutex.Lock()
+			delete(encodings, "reverse")
+			encodingsMutex.Unlock()
+		}()
+
+		result := &Output{}
+		err := Do(Params{URL: ts.URL, Method: http.MethodPost, Body: input, Compression: &Compression{Encoding: "Reverse"}}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "reverse:"+expected, result.ResponseValue)
+		assert.Equal(t, "deflate, gzip, reverse", acceptEncoding())
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL, Method: http.MethodPost, Body: input, Compression: &Compression{Encoding: "zstd"}}, nil)
+		assert.EqualError(t, err, `failed to compress request body: unknown content encoding "zstd"`)
+	})
+}
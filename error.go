@@ -0,0 +1,132 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fastbill/go-httperrors/v2"
+)
+
+// redactedValue replaces the values of query parameters in URLs that are part of an error.
+const redactedValue = "REDACTED"
+
+// Error is returned if the response status code was not 2xx or did not match the expected response code.
+// It wraps an HTTPError from the package httperrors, so errors.As can be used with both types.
+type Error struct {
+	*httperrors.HTTPError
+
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request without user info and with the query values redacted.
+	URL string
+	// Header contains the headers of the response.
+	Header http.Header
+	// Body is the response body. It is truncated if it was longer than MaxErrorBodyBytes
+	// of the request params or client, which defaults to 64 KiB.
+	Body []byte
+	// Truncated is true if Body does not hold the complete response body.
+	Truncated bool
+	// Problem holds the problem details if the response had the content type application/problem+json.
+	Problem *ProblemDetails
+	// DecodedBody is the body decoded into the ErrorBody or ErrorBodies target of the request or client.
+	// It is nil if no target was configured for the status code or the body could not be decoded.
+	DecodedBody interface{}
+	// ExpectedStatusCode is set if the request specified an expected response code that was not matched.
+	ExpectedStatusCode int
+	// Attempts is the number of attempts that were made to execute the request.
+	Attempts int
+	// Duration is the time from the first attempt until the error occurred.
+	Duration time.Duration
+}
+
+// Error returns the method and URL of the request followed by the status code and message.
+func (e *Error) Error() string {
+	if e.ExpectedStatusCode != 0 {
+		return fmt.Sprintf("%s %s: expected response code %d but got %d", e.Method, e.URL, e.ExpectedStatusCode, e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.HTTPError.Error())
+}
+
+// Unwrap returns the wrapped HTTPError.
+func (e *Error) Unwrap() error {
+	return e.HTTPError
+}
+
+// newError reads the body of the response and returns an Error with the metadata of the request.
+func newError(res *http.Response, expectedResponseCode int, maxBodyBytes int64) *Error {
+	body, truncated := readErrorBody(res.Body, maxBodyBytes)
+	result := &Error{
+		Header:    res.Header,
+		Body:      body,
+		Truncated: truncated,
+		Problem:   parseProblem(res.Header, body),
+		Attempts:  1,
+	}
+
+	if res.Request != nil {
+		result.Method = res.Request.Method
+		result.URL = redactURL(res.Request.URL)
+	}
+
+	switch {
+	case expectedResponseCode != 0 && res.StatusCode != expectedResponseCode:
+		result.ExpectedStatusCode = expectedResponseCode
+		result.HTTPError = httperrors.New(res.StatusCode, fmt.Sprintf("expected response code %d but got %d", expectedResponseCode, res.StatusCode))
+	case len(body) == 0:
+		result.HTTPError = httperrors.New(res.StatusCode, nil)
+	default:
+		result.HTTPError = httperrors.New(res.StatusCode, string(body))
+	}
+
+	return result
+}
+
+// readErrorBody reads up to maxBytes of the body and reports whether there was more.
+func readErrorBody(body io.Reader, maxBytes int64) ([]byte, bool) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, false
+	}
+
+	if int64(len(data)) > maxBytes {
+		return data[:maxBytes], true
+	}
+
+	return data, false
+}
+
+// asError returns the Error contained in err or nil if there is none.
+func asError(err error) *Error {
+	result := &Error{}
+	if errors.As(err, &result) {
+		return result
+	}
+
+	return nil
+}
+
+// redactURL removes the user info and replaces the query values so the URL can be logged safely.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	redacted.User = nil
+
+	query := redacted.Query()
+	for _, values := range query {
+		for i := range values {
+			values[i] = redactedValue
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
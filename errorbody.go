@@ -0,0 +1,70 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ErrorBodies maps status codes to the types the bodies of error responses are decoded into.
+// The keys are status codes like "404" or ranges like "4XX", exact status codes take precedence.
+// The values are only used as examples of the types, a new value is created for every response.
+type ErrorBodies map[string]interface{}
+
+// target returns a pointer to a new value of the type registered for the status code or nil if there is none.
+func (b ErrorBodies) target(statusCode int) interface{} {
+	example, ok := b[strconv.Itoa(statusCode)]
+	if !ok {
+		example, ok = b[fmt.Sprintf("%dXX", statusCode/100)]
+	}
+	if !ok {
+		example, ok = b[fmt.Sprintf("%dxx", statusCode/100)]
+	}
+	if !ok || example == nil {
+		return nil
+	}
+
+	exampleType := reflect.TypeOf(example)
+	if exampleType.Kind() == reflect.Ptr {
+		exampleType = exampleType.Elem()
+	}
+
+	return reflect.New(exampleType).Interface()
+}
+
+// errorBodyTarget returns the value the body of an error response with the status code should be decoded into.
+// params.ErrorBody takes precedence over params.ErrorBodies, which takes precedence over Client.ErrorBodies.
+// params.ErrorBody is a single shared pointer that is decoded into on every attempt and for every status code,
+// so params that set it must not be reused for concurrent requests.
+func (c *Client) errorBodyTarget(params Params, statusCode int) interface{} {
+	if params.ErrorBody != nil {
+		return params.ErrorBody
+	}
+
+	if target := params.ErrorBodies.target(statusCode); target != nil {
+		return target
+	}
+
+	return c.ErrorBodies.target(statusCode)
+}
+
+// decodeErrorBody decodes the body of the error response into the configured target.
+// If the body cannot be decoded, DecodedBody stays empty and only the raw body is available.
+func (c *Client) decodeErrorBody(params Params, err error) {
+	responseErr := asError(err)
+	if responseErr == nil || isSuccessCode(responseErr.StatusCode) || len(responseErr.Body) == 0 {
+		return
+	}
+
+	target := c.errorBodyTarget(params, responseErr.StatusCode)
+	if target == nil {
+		return
+	}
+
+	if err := c.responseCodec(params, responseErr.Header.Get("Content-Type")).Decode(bytes.NewReader(responseErr.Body), target); err != nil {
+		return
+	}
+
+	responseErr.DecodedBody = target
+}
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationError struct {
+	Fields map[string]string `json:"fields"`
+}
+
+type serverError struct {
+	Code string `json:"code"`
+}
+
+func newErrorBodyServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+		switch {
+		case status == http.StatusUnprocessableEntity:
+			_, _ = w.Write([]byte(`{"fields":{"name":"is required"}}`))
+		case status >= http.StatusInternalServerError:
+			_, _ = w.Write([]byte(`{"code":"database_down"}`))
+		default:
+			_, _ = w.Write([]byte(`not json`))
+		}
+	}))
+}
+
+func TestErrorBody(t *testing.T) {
+	ts := newErrorBodyServer()
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("decodes into the target of the params", func(t *testing.T) {
+		target := &validationError{}
+		err := Do(Params{URL: ts.URL + "?status=422", ErrorBody: target}, nil)
+
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Same(t, target, responseErr.DecodedBody)
+		assert.Equal(t, "is required", target.Fields["name"])
+	})
+
+	t.Run("decodes by status code and range", func(t *testing.T) {
+		client := NewClient()
+		client.ErrorBodies = ErrorBodies{"422": validationError{}, "5XX": &serverError{}}
+
+		err := client.Do(ctx, Params{URL: ts.URL + "?status=422"}, nil)
+		validation, ok := ErrorBodyAs[validationError](err)
+		require.True(t, ok)
+		assert.Equal(t, "is required", validation.Fields["name"])
+
+		err = client.Do(ctx, Params{URL: ts.URL + "?status=503"}, nil)
+		server, ok := ErrorBodyAs[serverError](err)
+		require.True(t, ok)
+		assert.Equal(t, "database_down", server.Code)
+
+		_, ok = ErrorBodyAs[validationError](err)
+		assert.False(t, ok)
+	})
+
+	t.Run("params take precedence over the client", func(t *testing.T) {
+		client := NewClient()
+		client.ErrorBodies = ErrorBodies{"5xx": validationError{}}
+
+		err := client.Do(ctx, Params{URL: ts.URL + "?status=500", ErrorBodies: ErrorBodies{"500": serverError{}}}, nil)
+		server, ok := ErrorBodyAs[serverError](err)
+		require.True(t, ok)
+		assert.Equal(t, "database_down", server.Code)
+	})
+
+	t.Run("keeps the raw body if it cannot be decoded", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL + "?status=400", ErrorBody: &validationError{}}, nil)
+
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Nil(t, responseErr.DecodedBody)
+		assert.Equal(t, "not json", responseErr.Message)
+	})
+
+	t.Run("no target for the status code", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL + "?status=404", ErrorBodies: ErrorBodies{"5XX": serverError{}}}, nil)
+
+		_, ok := ErrorBodyAs[serverError](err)
+		assert.False(t, ok)
+		_, ok = ErrorBodyAs[serverError](nil)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,220 @@
+package request
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FormBody is a request body that is sent as application/x-www-form-urlencoded regardless of the codec.
+// The response is still decoded with the codec matching its content type.
+type FormBody struct {
+	Value interface{}
+}
+
+// Form wraps the value so it is sent as form body. The value can be url.Values, a map with string keys
+// or a struct. Struct fields are named by the "form" tag, e.g. `form:"name,omitempty"`, fields tagged
+// with "-" are skipped. Nested structs and maps use the bracket notation "parent[child]",
+// slices are sent as multiple values with the same name.
+func Form(value interface{}) FormBody {
+	return FormBody{Value: value}
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// formValues converts the supported values to url.Values.
+func formValues(v interface{}) (url.Values, error) {
+	switch value := v.(type) {
+	case url.Values:
+		return value, nil
+	case map[string][]string:
+		return value, nil
+	case map[string]string:
+		values := url.Values{}
+		for key, item := range value {
+			values.Set(key, item)
+		}
+		return values, nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct && value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("cannot encode %T as form", v)
+	}
+
+	values := url.Values{}
+	err := encodeFormValue(values, "", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// encodeFormValue adds the value with the given name to the form values.
+func encodeFormValue(values url.Values, name string, value reflect.Value) error {
+	value, ok := indirectFormValue(value)
+	if !ok {
+		return nil
+	}
+
+	if value.CanInterface() && value.Type().Implements(textMarshalerType) {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return fmt.Errorf("failed to encode form field %s: %w", name, err)
+		}
+		values.Add(name, string(text))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return encodeFormStruct(values, name, value)
+	case reflect.Map:
+		return encodeFormMap(values, name, value)
+	case reflect.Slice, reflect.Array:
+		return encodeFormSlice(values, name, value)
+	}
+
+	text, err := formatFormScalar(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode form field %s: %w", name, err)
+	}
+	values.Add(name, text)
+	return nil
+}
+
+// indirectFormValue follows pointers and interfaces. It returns false if one of them is nil.
+func indirectFormValue(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+
+	return value, true
+}
+
+func encodeFormStruct(values url.Values, prefix string, value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		promoted := isPromotedField(field, value.Field(i))
+		if !field.IsExported() && !promoted {
+			continue
+		}
+
+		name, omitEmpty := parseFormTag(field)
+		if name == "-" || (omitEmpty && isEmptyFormValue(value.Field(i))) {
+			continue
+		}
+
+		fieldName := formName(prefix, name)
+		if promoted {
+			fieldName = prefix
+		}
+
+		err := encodeFormValue(values, fieldName, value.Field(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeFormMap(values url.Values, prefix string, value reflect.Value) error {
+	if value.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot encode map with key type %s as form", value.Type().Key())
+	}
+
+	iter := value.MapRange()
+	for iter.Next() {
+		err := encodeFormValue(values, formName(prefix, iter.Key().String()), iter.Value())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeFormSlice adds scalar elements as multiple values with the same name
+// and structured elements with their index, e.g. "items[0][name]".
+func encodeFormSlice(values url.Values, name string, value reflect.Value) error {
+	for i := 0; i < value.Len(); i++ {
+		element := reflect.Indirect(value.Index(i))
+		elementName := name
+		if element.Kind() == reflect.Struct || element.Kind() == reflect.Map {
+			elementName = fmt.Sprintf("%s[%d]", name, i)
+		}
+
+		err := encodeFormValue(values, elementName, value.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatFormScalar(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", value.Type())
+	}
+}
+
+// isPromotedField reports whether the field is an embedded struct without a name, its fields
+// are promoted like in encoding/json.
+func isPromotedField(field reflect.StructField, value reflect.Value) bool {
+	return field.Anonymous && field.Tag.Get("form") == "" && reflect.Indirect(value).Kind() == reflect.Struct
+}
+
+// parseFormTag returns the name of the field and whether the omitempty option is set.
+func parseFormTag(field reflect.StructField) (string, bool) {
+	name, options, _ := strings.Cut(field.Tag.Get("form"), ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+
+	return name, false
+}
+
+func formName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "[" + name + "]"
+}
+
+// isEmptyFormValue follows the definition of empty values of encoding/json.
+func isEmptyFormValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
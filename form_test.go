@@ -0,0 +1,130 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formAddress struct {
+	Street string `form:"street"`
+	City   string `form:"city,omitempty"`
+}
+
+type formItem struct {
+	Name     string `form:"name"`
+	Quantity int    `form:"quantity"`
+}
+
+type formMeta struct {
+	Source string `form:"source"`
+}
+
+type formInput struct {
+	formMeta
+	GrantType string            `form:"grant_type"`
+	Scopes    []string          `form:"scope"`
+	Optional  string            `form:"optional,omitempty"`
+	Empty     string            `form:"empty"`
+	Skipped   string            `form:"-"`
+	Amount    float64           `form:"amount"`
+	Active    bool              `form:"active"`
+	Count     *uint             `form:"count,omitempty"`
+	Address   *formAddress      `form:"address"`
+	Items     []formItem        `form:"items"`
+	Labels    map[string]string `form:"labels"`
+	Created   time.Time         `form:"created"`
+	Untagged  string
+	private   string
+}
+
+func TestFormValues(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		input := &formInput{
+			formMeta:  formMeta{Source: "test"},
+			GrantType: "client_credentials",
+			Scopes:    []string{"read", "write"},
+			Skipped:   "skipped",
+			Amount:    12.5,
+			Active:    true,
+			Address:   &formAddress{Street: "Main Street"},
+			Items:     []formItem{{Name: "a", Quantity: 1}, {Name: "b", Quantity: 2}},
+			Labels:    map[string]string{"env": "prod"},
+			Created:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			Untagged:  "value",
+			private:   "private",
+		}
+
+		values, err := formValues(input)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"source":             {"test"},
+			"grant_type":         {"client_credentials"},
+			"scope":              {"read", "write"},
+			"empty":              {""},
+			"amount":             {"12.5"},
+			"active":             {"true"},
+			"address[street]":    {"Main Street"},
+			"items[0][name]":     {"a"},
+			"items[0][quantity]": {"1"},
+			"items[1][name]":     {"b"},
+			"items[1][quantity]": {"2"},
+			"labels[env]":        {"prod"},
+			"created":            {"2020-01-02T03:04:05Z"},
+			"Untagged":           {"value"},
+		}, values)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		values, err := formValues(map[string]interface{}{"a": 1, "b": []int{2, 3}, "c": map[string]bool{"d": false}})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"a": {"1"}, "b": {"2", "3"}, "c[d]": {"false"}}, values)
+	})
+
+	t.Run("unsupported values", func(t *testing.T) {
+		_, err := formValues("string")
+		assert.Error(t, err)
+
+		_, err = formValues(map[int]string{1: "a"})
+		assert.Error(t, err)
+
+		_, err = formValues(map[string]interface{}{"a": func() {}})
+		assert.EqualError(t, err, "failed to encode form field a: unsupported type func()")
+	})
+}
+
+func TestFormBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.Equal(t, "application/json, application/problem+json", r.Header.Get("Accept"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "grant_type=client_credentials&scope=read&scope=write", string(body))
+		_, err = w.Write([]byte(`{"responseValue":"someValueOut"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	type tokenRequest struct {
+		GrantType string   `form:"grant_type"`
+		Scopes    []string `form:"scope,omitempty"`
+		Audience  string   `form:"audience,omitempty"`
+	}
+
+	result := &Output{}
+	err := Post(ts.URL, Form(tokenRequest{GrantType: "client_credentials", Scopes: []string{"read", "write"}}), result)
+	require.NoError(t, err)
+	assert.Equal(t, "someValueOut", result.ResponseValue)
+
+	err = Post(ts.URL, Form(url.Values{"grant_type": {"client_credentials"}, "scope": {"read", "write"}}), nil)
+	require.NoError(t, err)
+
+	err = Post(ts.URL, Form(42), nil)
+	assert.Contains(t, err.Error(), "failed to parse request body to x-www-form-urlencoded")
+}
@@ -0,0 +1,65 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// TypedResponse holds the decoded response body together with the metadata of the response.
+type TypedResponse[T any] struct {
+	*Response
+	Body T
+}
+
+// DoJSON executes the request with the default client and returns the decoded response body.
+// An empty response body results in the zero value of T.
+func DoJSON[T any](ctx context.Context, params Params) (T, error) {
+	res, err := DoJSONWithClient[T](ctx, getDefaultClient(), params)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return res.Body, nil
+}
+
+// DoJSONResponse is the same as DoJSON but returns the response metadata as well.
+func DoJSONResponse[T any](ctx context.Context, params Params) (*TypedResponse[T], error) {
+	return DoJSONWithClient[T](ctx, getDefaultClient(), params)
+}
+
+// DoJSONWithClient is the same as DoJSONResponse but uses the provided client.
+// The response body is decoded with the codec matching its content type.
+func DoJSONWithClient[T any](ctx context.Context, client *Client, params Params) (*TypedResponse[T], error) {
+	result := &TypedResponse[T]{}
+	res, err := client.do(ctx, params, &result.Body)
+	if err != nil && !(res != nil && errors.Is(err, io.EOF)) {
+		return nil, err
+	}
+
+	result.Response = res
+	return result, nil
+}
+
+// GetJSON is a convenience wrapper for "DoJSON" to execute GET requests
+func GetJSON[T any](ctx context.Context, url string) (T, error) {
+	return DoJSON[T](ctx, Params{Method: http.MethodGet, URL: url})
+}
+
+// PostJSON is a convenience wrapper for "DoJSON" to execute POST requests
+func PostJSON[Req any, Res any](ctx context.Context, url string, requestBody Req) (Res, error) {
+	return DoJSON[Res](ctx, Params{Method: http.MethodPost, URL: url, Body: requestBody})
+}
+
+// ErrorBodyAs returns the decoded body of the error response if it has the type T.
+func ErrorBodyAs[T any](err error) (*T, bool) {
+	responseErr := asError(err)
+	if responseErr == nil {
+		return nil, false
+	}
+
+	body, ok := responseErr.DecodedBody.(*T)
+	return body, ok
+}
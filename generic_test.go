@@ -0,0 +1,85 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/error":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/list":
+			_, err := w.Write([]byte(`[{"responseValue":"a"},{"responseValue":"b"}]`))
+			assert.NoError(t, err)
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Method == http.MethodPost {
+				assert.Equal(t, `{"requestValue":"someValueIn"}`+"\n", string(body))
+			}
+			w.Header().Set("Some-Header", "someHeaderValue")
+			w.WriteHeader(http.StatusCreated)
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("struct", func(t *testing.T) {
+		result, err := DoJSON[Output](ctx, Params{URL: ts.URL})
+		assert.NoError(t, err)
+		assert.Equal(t, Output{ResponseValue: "someValueOut"}, result)
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		result, err := GetJSON[[]Output](ctx, ts.URL+"/list")
+		assert.NoError(t, err)
+		assert.Equal(t, []Output{{ResponseValue: "a"}, {ResponseValue: "b"}}, result)
+	})
+
+	t.Run("post", func(t *testing.T) {
+		result, err := PostJSON[Input, *Output](ctx, ts.URL, Input{RequestValue: "someValueIn"})
+		assert.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		result, err := DoJSON[map[string]string](ctx, Params{URL: ts.URL + "/empty"})
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		result, err := DoJSON[Output](ctx, Params{URL: ts.URL + "/error"})
+		assert.IsType(t, &Error{}, err)
+		assert.Equal(t, Output{}, result)
+	})
+
+	t.Run("response", func(t *testing.T) {
+		res, err := DoJSONResponse[Output](ctx, Params{URL: ts.URL})
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", res.Body.ResponseValue)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "someHeaderValue", res.Header.Get("Some-Header"))
+		assert.True(t, res.Duration > 0)
+	})
+
+	t.Run("with client", func(t *testing.T) {
+		client := NewClient()
+		client.BaseURL = ts.URL
+		res, err := DoJSONWithClient[Output](ctx, client, Params{URL: "/item"})
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", res.Body.ResponseValue)
+	})
+}
@@ -0,0 +1,208 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// ndjsonContentTypes are the media types of newline-delimited JSON.
+var ndjsonContentTypes = map[string]bool{
+	"application/x-ndjson":    true,
+	"application/ndjson":      true,
+	"application/jsonl":       true,
+	"application/x-jsonlines": true,
+}
+
+// ElementError is returned by a StreamIterator if an element could not be read or decoded.
+type ElementError struct {
+	// Index is the zero-based position of the element in the stream.
+	Index int
+	Err   error
+}
+
+// Error returns the index of the element together with the message of the wrapped error.
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("failed to decode element %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// StreamIterator yields the elements of a JSON array or of newline-delimited JSON one at a time,
+// so large responses do not need to be held in memory. It must be closed once it is not needed anymore.
+//
+//	it, err := request.Stream[Row](ctx, params)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		row := it.Value()
+//	}
+//	return it.Err()
+type StreamIterator[T any] struct {
+	response *Response
+	body     io.Closer
+	cancel   context.CancelFunc
+	next     func() (T, error)
+	value    T
+	index    int
+	err      error
+	closed   bool
+}
+
+// Stream executes the request with the default client and returns an iterator over the elements of the response body.
+// Responses with an NDJSON content type like application/x-ndjson are decoded line by line,
+// all other responses are expected to contain a JSON array.
+func Stream[T any](ctx context.Context, params Params) (*StreamIterator[T], error) {
+	return StreamWithClient[T](ctx, getDefaultClient(), params)
+}
+
+// StreamWithClient is the same as Stream but uses the provided client.
+func StreamWithClient[T any](ctx context.Context, client *Client, params Params) (*StreamIterator[T], error) {
+	res, cancel, err := client.open(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &StreamIterator[T]{
+		response: newResponse(res),
+		body:     res.Body,
+		cancel:   cancel,
+	}
+
+	reader := client.responseBodyReader(res, params)
+	if isNDJSON(res.Header) {
+		it.next = ndjsonDecoder[T](bufio.NewReader(reader))
+	} else {
+		it.next = jsonArrayDecoder[T](json.NewDecoder(reader))
+	}
+
+	return it, nil
+}
+
+// Next advances to the next element. It returns false at the end of the stream or if an error occurred,
+// the iterator is closed in both cases.
+func (it *StreamIterator[T]) Next() bool {
+	if it.closed {
+		return false
+	}
+
+	value, err := it.next()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			it.err = &ElementError{Index: it.index, Err: err}
+		}
+		_ = it.Close()
+		return false
+	}
+
+	it.value = value
+	it.index++
+	return true
+}
+
+// Value returns the current element.
+func (it *StreamIterator[T]) Value() T {
+	return it.value
+}
+
+// Err returns the error that stopped the iteration, if any. It is nil if the end of the stream was reached
+// or the iterator was closed early.
+func (it *StreamIterator[T]) Err() error {
+	return it.err
+}
+
+// Response returns the metadata of the response.
+func (it *StreamIterator[T]) Response() *Response {
+	return it.response
+}
+
+// Close stops the iteration and closes the response body. It can be called more than once.
+func (it *StreamIterator[T]) Close() error {
+	if it.closed {
+		return nil
+	}
+
+	it.closed = true
+	err := it.body.Close()
+	it.cancel()
+	return err
+}
+
+func isNDJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && ndjsonContentTypes[mediaType]
+}
+
+// jsonArrayDecoder returns the elements of a JSON array using the tokens of the decoder
+// and io.EOF once the end of the array was reached. An empty body is not an array and leads to an error.
+func jsonArrayDecoder[T any](decoder *json.Decoder) func() (T, error) {
+	started := false
+	return func() (T, error) {
+		var value T
+		if !started {
+			token, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				return value, fmt.Errorf("expected JSON array but the body is empty: %w", io.ErrUnexpectedEOF)
+			}
+			if err != nil {
+				return value, err
+			}
+			if delim, ok := token.(json.Delim); !ok || delim != '[' {
+				return value, fmt.Errorf("expected JSON array but got %v", token)
+			}
+			started = true
+		}
+
+		if !decoder.More() {
+			return value, endOfArray(decoder)
+		}
+
+		err := decoder.Decode(&value)
+		return value, err
+	}
+}
+
+// endOfArray reads the closing bracket of the array and returns io.EOF if it was found.
+func endOfArray(decoder *json.Decoder) error {
+	_, err := decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// ndjsonDecoder returns the elements of newline-delimited JSON and io.EOF at the end. Empty lines are skipped.
+func ndjsonDecoder[T any](reader *bufio.Reader) func() (T, error) {
+	return func() (T, error) {
+		var value T
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return value, err
+			}
+
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				return value, json.Unmarshal(line, &value)
+			}
+			if err != nil {
+				return value, err
+			}
+		}
+	}
+}
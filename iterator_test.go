@@ -0,0 +1,114 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamElement struct {
+	ID int `json:"id"`
+}
+
+func newStreamServer() *httptest.Server {
+	responses := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"/array":      {"application/json", ` [{"id":1}, {"id":2},{"id":3}] `},
+		"/empty":      {"application/json", `[]`},
+		"/no-body":    {"application/json", ``},
+		"/object":     {"application/json", `{"id":1}`},
+		"/invalid":    {"application/json", `[{"id":1},{"id":"two"},{"id":3}]`},
+		"/truncated":  {"application/json", `[{"id":1},{"id":2}`},
+		"/ndjson":     {"application/x-ndjson", "{\"id\":1}\n\n{\"id\":2}\r\n{\"id\":3}"},
+		"/ndjson-bad": {"application/x-ndjson", "{\"id\":1}\nnot json\n{\"id\":3}\n"},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", response.contentType)
+		_, _ = w.Write([]byte(response.body))
+	}))
+}
+
+func collect[T any](it *StreamIterator[T]) []T {
+	result := []T{}
+	for it.Next() {
+		result = append(result, it.Value())
+	}
+	return result
+}
+
+func TestStream(t *testing.T) {
+	ts := newStreamServer()
+	defer ts.Close()
+	ctx := context.Background()
+
+	for _, path := range []string{"/array", "/ndjson"} {
+		t.Run("elements "+path, func(t *testing.T) {
+			it, err := Stream[streamElement](ctx, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			defer it.Close()
+
+			assert.Equal(t, []streamElement{{1}, {2}, {3}}, collect(it))
+			assert.NoError(t, it.Err())
+			assert.Equal(t, http.StatusOK, it.Response().StatusCode)
+			assert.False(t, it.Next())
+		})
+	}
+
+	t.Run("empty array", func(t *testing.T) {
+		it, err := Stream[streamElement](ctx, Params{URL: ts.URL + "/empty"})
+		require.NoError(t, err)
+		assert.Empty(t, collect(it))
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		it, err := Stream[streamElement](ctx, Params{URL: ts.URL + "/no-body"})
+		require.NoError(t, err)
+		assert.Empty(t, collect(it))
+		assert.True(t, errors.Is(it.Err(), io.ErrUnexpectedEOF))
+	})
+
+	t.Run("early termination", func(t *testing.T) {
+		it, err := StreamWithClient[streamElement](ctx, NewClient(), Params{URL: ts.URL + "/array"})
+		require.NoError(t, err)
+		require.True(t, it.Next())
+		assert.Equal(t, 1, it.Value().ID)
+		assert.NoError(t, it.Close())
+		assert.NoError(t, it.Close())
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("element errors", func(t *testing.T) {
+		for path, index := range map[string]int{"/invalid": 1, "/ndjson-bad": 1, "/truncated": 2, "/object": 0} {
+			it, err := Stream[streamElement](ctx, Params{URL: ts.URL + path})
+			require.NoError(t, err)
+			assert.Len(t, collect(it), index, path)
+
+			elementErr := &ElementError{}
+			require.True(t, errors.As(it.Err(), &elementErr), path)
+			assert.Equal(t, index, elementErr.Index, path)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		_, err := Stream[streamElement](ctx, Params{URL: ts.URL + "/missing"})
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusNotFound, responseErr.StatusCode)
+	})
+}
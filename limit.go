@@ -0,0 +1,85 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// defaultMaxErrorBodyBytes limits how much of an error response body is read into an Error
+// if neither the request params nor the client specify a limit.
+const defaultMaxErrorBodyBytes = 64 << 10
+
+// ErrResponseTooLarge is matched by errors.Is for every ResponseTooLargeError.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ResponseTooLargeError is returned if the response body exceeds MaxResponseBytes of the request params or client.
+// The limit applies to the decompressed body, so compressed responses can not bypass it.
+type ResponseTooLargeError struct {
+	// Limit is the maximum number of bytes that were allowed.
+	Limit int64
+	// Read is the number of bytes that were read from the body until the limit was exceeded.
+	Read int64
+}
+
+// Error returns the limit and the number of bytes that were read.
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s: read %d bytes but the limit is %d bytes", ErrResponseTooLarge, e.Read, e.Limit)
+}
+
+// Is reports whether the target is ErrResponseTooLarge.
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// maxBytesReader fails with a ResponseTooLargeError once more than the allowed bytes were read.
+type maxBytesReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, r.err()
+	}
+
+	// Read one byte more than allowed to find out if the body is too large.
+	if remaining := r.limit - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n - int(r.read-r.limit), r.err()
+	}
+
+	return n, err
+}
+
+func (r *maxBytesReader) err() error {
+	return &ResponseTooLargeError{Limit: r.limit, Read: r.read}
+}
+
+// maxResponseBytes returns the limit of the params or, if there is none, the limit of the client.
+func (c *Client) maxResponseBytes(params Params) int64 {
+	if params.MaxResponseBytes > 0 {
+		return params.MaxResponseBytes
+	}
+
+	return c.MaxResponseBytes
+}
+
+// maxErrorBodyBytes returns the limit for error bodies of the params, the client or the default limit.
+func (c *Client) maxErrorBodyBytes(params Params) int64 {
+	if params.MaxErrorBodyBytes > 0 {
+		return params.MaxErrorBodyBytes
+	}
+
+	if c.MaxErrorBodyBytes > 0 {
+		return c.MaxErrorBodyBytes
+	}
+
+	return defaultMaxErrorBodyBytes
+}
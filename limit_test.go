@@ -0,0 +1,94 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxBytesReader(t *testing.T) {
+	reader := &maxBytesReader{reader: strings.NewReader("0123456789"), limit: 4}
+	data, err := ioutil.ReadAll(reader)
+	assert.Equal(t, "0123", string(data))
+
+	tooLarge := &ResponseTooLargeError{}
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, int64(4), tooLarge.Limit)
+	assert.Equal(t, int64(5), tooLarge.Read)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	assert.EqualError(t, err, "response body too large: read 5 bytes but the limit is 4 bytes")
+
+	reader = &maxBytesReader{reader: strings.NewReader("0123"), limit: 4}
+	data, err = ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123", string(data))
+}
+
+func TestResponseSizeLimits(t *testing.T) {
+	body := `{"responseValue":"` + strings.Repeat("a", 1000) + `"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(compressed(t, "gzip", body))
+			return
+		}
+		if r.URL.Query().Get("status") == "error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("params", func(t *testing.T) {
+		err := Do(Params{URL: ts.URL, MaxResponseBytes: 100}, &Output{})
+		assert.True(t, errors.Is(err, ErrResponseTooLarge))
+
+		err = Do(Params{URL: ts.URL, MaxResponseBytes: int64(len(body))}, &Output{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("client", func(t *testing.T) {
+		client := NewClient()
+		client.MaxResponseBytes = 100
+
+		_, err := client.DoWithStringResponse(ctx, Params{URL: ts.URL})
+		tooLarge := &ResponseTooLargeError{}
+		require.True(t, errors.As(err, &tooLarge))
+		assert.Equal(t, int64(100), tooLarge.Limit)
+
+		_, err = client.DoWithStringResponse(ctx, Params{URL: ts.URL, MaxResponseBytes: 2000})
+		assert.NoError(t, err)
+	})
+
+	t.Run("applies to the decompressed body", func(t *testing.T) {
+		require.Less(t, len(compressed(t, "gzip", body)), 100)
+		err := Do(Params{URL: ts.URL, Query: map[string]string{"encoding": "gzip"}, MaxResponseBytes: 100}, &Output{})
+		assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	})
+
+	t.Run("error body", func(t *testing.T) {
+		client := NewClient()
+		client.MaxErrorBodyBytes = 10
+		client.MaxResponseBytes = 1
+
+		err := client.Do(ctx, Params{URL: ts.URL, Query: map[string]string{"status": "error"}}, nil)
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, body[:10], string(responseErr.Body))
+		assert.True(t, responseErr.Truncated)
+
+		err = client.Do(ctx, Params{URL: ts.URL, Query: map[string]string{"status": "error"}, MaxErrorBodyBytes: 2000}, nil)
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, body, string(responseErr.Body))
+		assert.False(t, responseErr.Truncated)
+	})
+}
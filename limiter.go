@@ -0,0 +1,184 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiterSettings configures a rate limiter.
+type RateLimiterSettings struct {
+	// Rate is the number of requests per second that are allowed per key.
+	// A rate of zero or less disables the limit.
+	Rate float64
+
+	// Burst is the number of requests that can be made at once. Defaults to 1.
+	Burst int
+
+	// FailFast makes requests that exceed the limit fail with ErrRateLimited instead
+	// of waiting for the next free slot.
+	FailFast bool
+}
+
+// RateLimiter limits the number of requests per key using the token bucket algorithm.
+// By default the host of the request is used as key. A rate limiter can be shared
+// between clients and the limits can be changed while it is in use.
+type RateLimiter struct {
+	mutex    sync.Mutex
+	rate     float64
+	burst    int
+	failFast bool
+	limits   map[string]tokenLimit
+	buckets  map[string]*tokenBucket
+	now      func() time.Time
+}
+
+type tokenLimit struct {
+	rate  float64
+	burst int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a rate limiter with the given settings.
+func NewRateLimiter(settings RateLimiterSettings) *RateLimiter {
+	if settings.Burst <= 0 {
+		settings.Burst = 1
+	}
+
+	return &RateLimiter{
+		rate:     settings.Rate,
+		burst:    settings.Burst,
+		failFast: settings.FailFast,
+		limits:   map[string]tokenLimit{},
+		buckets:  map[string]*tokenBucket{},
+		now:      time.Now,
+	}
+}
+
+// SetLimit changes the rate and burst for all keys that do not have their own limit.
+func (l *RateLimiter) SetLimit(rate float64, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rate = rate
+	l.burst = burst
+}
+
+// SetKeyLimit sets the rate and burst for a single key, e.g. a host.
+func (l *RateLimiter) SetKeyLimit(key string, rate float64, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limits[key] = tokenLimit{rate: rate, burst: burst}
+}
+
+// Allow takes a token for the key if one is available and reports whether that was the case.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, limit := l.bucket(key)
+	if limit.rate <= 0 {
+		return true
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+	return true
+}
+
+// Wait blocks until a token for the key is available. If that is not possible before the deadline
+// of the context or if the limiter is configured to fail fast, ErrRateLimited is returned.
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	delay, ok := l.reserve(ctx, key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRateLimited, key)
+	}
+
+	if delay <= 0 {
+		return nil
+	}
+
+	err := sleep(ctx, delay)
+	if err != nil {
+		l.cancel(key)
+	}
+
+	return err
+}
+
+// reserve takes a token for the key and returns how long to wait until it can be used.
+func (l *RateLimiter) reserve(ctx context.Context, key string) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, limit := l.bucket(key)
+	if limit.rate <= 0 {
+		return 0, true
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+
+	if l.failFast {
+		return 0, false
+	}
+
+	delay := time.Duration(math.Ceil((1 - bucket.tokens) / limit.rate * float64(time.Second)))
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(l.now().Add(delay)) {
+		return 0, false
+	}
+
+	bucket.tokens--
+	return delay, true
+}
+
+// cancel returns a reserved token that was not used.
+func (l *RateLimiter) cancel(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, _ := l.bucket(key)
+	bucket.tokens++
+}
+
+// bucket returns the bucket of the key with the tokens refilled up to now.
+// The mutex must be held by the caller.
+func (l *RateLimiter) bucket(key string) (*tokenBucket, tokenLimit) {
+	limit, ok := l.limits[key]
+	if !ok {
+		limit = tokenLimit{rate: l.rate, burst: l.burst}
+	}
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	if limit.rate > 0 && now.After(bucket.last) {
+		bucket.tokens += now.Sub(bucket.last).Seconds() * limit.rate
+	}
+	bucket.tokens = math.Min(bucket.tokens, float64(limit.burst))
+	bucket.last = now
+
+	return bucket, limit
+}
@@ -0,0 +1,153 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimiter(settings RateLimiterSettings) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(settings)
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimiterSettings{Rate: 2, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow("key"))
+	}
+	assert.False(t, limiter.Allow("key"))
+
+	// Other keys have their own bucket.
+	assert.True(t, limiter.Allow("other"))
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, limiter.Allow("key"))
+	assert.False(t, limiter.Allow("key"))
+
+	// The bucket does not fill up beyond the burst.
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow("key"))
+	}
+	assert.False(t, limiter.Allow("key"))
+}
+
+func TestRateLimiterSetLimit(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimiterSettings{Rate: 1, Burst: 1})
+	limiter.SetKeyLimit("special", 10, 5)
+
+	assert.True(t, limiter.Allow("key"))
+	assert.False(t, limiter.Allow("key"))
+	for i := 0; i < 5; i++ {
+		assert.True(t, limiter.Allow("special"))
+	}
+	assert.False(t, limiter.Allow("special"))
+
+	limiter.SetLimit(0, 1)
+	assert.True(t, limiter.Allow("key"))
+	assert.True(t, limiter.Allow("key"))
+
+	limiter.SetLimit(1, 2)
+	clock.Advance(time.Hour)
+	assert.True(t, limiter.Allow("key"))
+	assert.True(t, limiter.Allow("key"))
+	assert.False(t, limiter.Allow("key"))
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	t.Run("waits for the next token", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimiterSettings{Rate: 50})
+		require.NoError(t, limiter.Wait(context.Background(), "key"))
+
+		start := time.Now()
+		require.NoError(t, limiter.Wait(context.Background(), "key"))
+		assert.True(t, time.Since(start) >= 15*time.Millisecond)
+	})
+
+	t.Run("fails if the deadline is too short", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimiterSettings{Rate: 1})
+		require.NoError(t, limiter.Wait(context.Background(), "key"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := limiter.Wait(ctx, "key")
+		assert.True(t, errors.Is(err, ErrRateLimited))
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimiterSettings{Rate: 1, FailFast: true})
+		require.NoError(t, limiter.Wait(context.Background(), "key"))
+		err := limiter.Wait(context.Background(), "key")
+		assert.True(t, errors.Is(err, ErrRateLimited))
+	})
+
+	t.Run("unlimited requests do not use up the tokens", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimiterSettings{})
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, limiter.Wait(context.Background(), "key"))
+		}
+
+		limiter.SetLimit(10, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		start := time.Now()
+		require.NoError(t, limiter.Wait(ctx, "key"))
+		assert.True(t, time.Since(start) < time.Second)
+	})
+
+	t.Run("returns the token if the context is cancelled", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter(RateLimiterSettings{Rate: 0.001})
+		require.NoError(t, limiter.Wait(context.Background(), "key"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(5*time.Millisecond, cancel)
+		err := limiter.Wait(ctx, "key")
+		assert.True(t, errors.Is(err, context.Canceled))
+
+		bucket, _ := limiter.bucket("key")
+		assert.Equal(t, float64(0), bucket.tokens)
+	})
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	calls := int32(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer ts.Close()
+
+	client := NewClient()
+	client.RateLimiter = NewRateLimiter(RateLimiterSettings{Rate: 0.001, Burst: 2, FailFast: true})
+	ctx := context.Background()
+
+	assert.NoError(t, client.Get(ctx, ts.URL, nil))
+	assert.NoError(t, client.Get(ctx, ts.URL, nil))
+	err := client.Get(ctx, ts.URL, nil)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	t.Run("custom key", func(t *testing.T) {
+		err := client.Do(ctx, Params{URL: ts.URL, RateLimitKey: "other"}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("limiter in the params", func(t *testing.T) {
+		params := Params{
+			URL:         ts.URL,
+			RateLimiter: NewRateLimiter(RateLimiterSettings{}),
+		}
+		err := client.Do(ctx, params, nil)
+		assert.NoError(t, err)
+	})
+}
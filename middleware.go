@@ -0,0 +1,107 @@
+package request
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// defaultRequestIDHeader is the header used by the RequestID middleware if there is none provided.
+const defaultRequestIDHeader = "X-Request-ID"
+
+// Handler sends a request and returns the response.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a handler to act on the request before it is sent
+// or on the response before its status code is checked.
+type Middleware func(next Handler) Handler
+
+// Chain combines the middlewares into one. The first middleware is the outermost one,
+// so it sees the request first and the response last.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// handler returns the handler for a single attempt of the request. The middlewares of
+// the client wrap the ones of the request params, the rate limits and the circuit breaker
+// are applied after all middlewares ran.
+func (c *Client) handler(params Params) Handler {
+	send := func(req *http.Request) (*http.Response, error) {
+		return c.roundTrip(req, params)
+	}
+
+	return Chain(c.Middlewares...)(Chain(params.Middlewares...)(send))
+}
+
+// Logging logs every request with method, URL, status code and duration.
+// The query of the URL is not logged as it might contain sensitive data.
+func Logging(logf func(format string, v ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next(req)
+			duration := time.Since(start)
+
+			u := *req.URL
+			u.RawQuery = ""
+			u.User = nil
+			if err != nil {
+				logf("%s %s failed after %s: %s", req.Method, u.String(), duration, err)
+				return res, err
+			}
+
+			logf("%s %s %d %s", req.Method, u.String(), res.StatusCode, duration)
+			return res, nil
+		}
+	}
+}
+
+// BearerAuth sets the Authorization header with the given token.
+func BearerAuth(token string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next(req)
+		}
+	}
+}
+
+// BasicAuth sets the Authorization header with the given credentials.
+func BasicAuth(username string, password string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.SetBasicAuth(username, password)
+			return next(req)
+		}
+	}
+}
+
+// RequestID sets a random request ID in the given header unless the request already has one.
+// If the header is empty, X-Request-ID is used.
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = defaultRequestIDHeader
+	}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				req.Header.Set(header, newRequestID())
+			}
+			return next(req)
+		}
+	}
+}
+
+// newRequestID returns 16 random bytes in hex encoding.
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
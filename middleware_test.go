@@ -0,0 +1,156 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMiddleware appends the name to the calls before and after the request.
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+" before")
+			res, err := next(req)
+			*calls = append(*calls, name+" after")
+			return res, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	calls := []string{}
+	client := NewClient()
+	client.Middlewares = []Middleware{
+		recordingMiddleware("client1", &calls),
+		recordingMiddleware("client2", &calls),
+	}
+
+	params := Params{
+		URL:         ts.URL,
+		Middlewares: []Middleware{recordingMiddleware("params", &calls)},
+	}
+
+	err := client.Do(context.Background(), params, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"client1 before",
+		"client2 before",
+		"params before",
+		"params after",
+		"client2 after",
+		"client1 after",
+	}, calls)
+}
+
+func TestMiddlewareResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	notFoundIsOK := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			res, err := next(req)
+			if err == nil && res.StatusCode == http.StatusNotFound {
+				res.StatusCode = http.StatusNoContent
+			}
+			return res, err
+		}
+	}
+
+	err := Do(Params{URL: ts.URL, Middlewares: []Middleware{notFoundIsOK}}, nil)
+	assert.NoError(t, err)
+}
+
+func TestMiddlewareError(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	errAborted := errors.New("aborted")
+	abort := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, errAborted
+		}
+	}
+
+	err := Do(Params{URL: ts.URL, Middlewares: []Middleware{abort}}, nil)
+	assert.True(t, errors.Is(err, errAborted))
+	assert.Equal(t, 0, calls)
+}
+
+func TestBuiltInMiddlewares(t *testing.T) {
+	t.Run("auth", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if ok {
+				assert.Equal(t, "user", username)
+				assert.Equal(t, "secret", password)
+				return
+			}
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		}))
+		defer ts.Close()
+
+		assert.NoError(t, Do(Params{URL: ts.URL, Middlewares: []Middleware{BearerAuth("token")}}, nil))
+		assert.NoError(t, Do(Params{URL: ts.URL, Middlewares: []Middleware{BasicAuth("user", "secret")}}, nil))
+	})
+
+	t.Run("request id", func(t *testing.T) {
+		ids := []string{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, r.Header.Get("X-Request-ID"))
+		}))
+		defer ts.Close()
+
+		params := Params{URL: ts.URL, Middlewares: []Middleware{RequestID("")}}
+		require.NoError(t, Do(params, nil))
+		require.NoError(t, Do(params, nil))
+		params.Headers = map[string]string{"X-Request-ID": "given"}
+		require.NoError(t, Do(params, nil))
+
+		require.Len(t, ids, 3)
+		assert.Len(t, ids[0], 32)
+		assert.NotEqual(t, ids[0], ids[1])
+		assert.Equal(t, "given", ids[2])
+	})
+
+	t.Run("logging", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer ts.Close()
+
+		lines := []string{}
+		logf := func(format string, v ...interface{}) {
+			lines = append(lines, fmt.Sprintf(format, v...))
+		}
+
+		params := Params{
+			URL:         ts.URL + "/path?token=secret",
+			Method:      http.MethodPost,
+			Middlewares: []Middleware{Logging(logf)},
+		}
+		require.NoError(t, Do(params, nil))
+		require.Len(t, lines, 1)
+		assert.Contains(t, lines[0], "POST "+ts.URL+"/path 202")
+		assert.NotContains(t, lines[0], "secret")
+
+		params.URL = "http://"
+		assert.Error(t, Do(params, nil))
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[1], "failed after")
+	})
+}
@@ -0,0 +1,169 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultFileContentType is used for file parts if the content type is not known.
+const defaultFileContentType = "application/octet-stream"
+
+// Multipart builds a multipart/form-data request body. The body is streamed while the
+// request is sent, so files are never fully held in memory. As the parts are read
+// while sending, a multipart body can only be sent once and requests using it are not retried.
+// Contents that implement io.Closer are closed once they were sent.
+type Multipart struct {
+	parts []multipartPart
+
+	// OnProgress is called whenever a chunk of the body was sent with the total number of bytes sent so far.
+	OnProgress func(sent int64)
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	// open returns the content of the part. If the content is a closer, it is closed after it was written.
+	open func() (io.Reader, error)
+}
+
+// NewMultipart returns an empty multipart body.
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+// AddField adds a text field.
+func (m *Multipart) AddField(name string, value string) *Multipart {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", formDataDisposition(name, ""))
+	return m.AddPart(header, strings.NewReader(value))
+}
+
+// AddFile adds a file part with the content of the reader. The content type is derived from the
+// extension of the file name and falls back to application/octet-stream.
+func (m *Multipart) AddFile(fieldName string, fileName string, content io.Reader) *Multipart {
+	return m.AddFileWithContentType(fieldName, fileName, fileContentType(fileName), content)
+}
+
+// AddFileWithContentType adds a file part with the given content type.
+func (m *Multipart) AddFileWithContentType(fieldName string, fileName string, contentType string, content io.Reader) *Multipart {
+	return m.AddPart(fileHeader(fieldName, fileName, contentType), content)
+}
+
+// AddFileFromPath adds a file part with the content of the file at the path. The file is opened
+// when the body is sent, an error opening it makes the request fail.
+func (m *Multipart) AddFileFromPath(fieldName string, path string) *Multipart {
+	fileName := filepath.Base(path)
+	m.parts = append(m.parts, multipartPart{
+		header: fileHeader(fieldName, fileName, fileContentType(fileName)),
+		open: func() (io.Reader, error) {
+			return os.Open(filepath.Clean(path))
+		},
+	})
+	return m
+}
+
+// AddPart adds a part with custom headers, e.g. to set a Content-Disposition or Content-Type
+// that the other methods do not support.
+func (m *Multipart) AddPart(header textproto.MIMEHeader, content io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{
+		header: header,
+		open: func() (io.Reader, error) {
+			return content, nil
+		},
+	})
+	return m
+}
+
+// reader starts writing the body in the background and returns the reading end together with the content type.
+func (m *Multipart) reader() (*io.PipeReader, string) {
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(&progressWriter{writer: pipeWriter, onProgress: m.OnProgress})
+
+	go func() {
+		pipeWriter.CloseWithError(m.write(writer))
+	}()
+
+	return pipeReader, writer.FormDataContentType()
+}
+
+// write writes all parts and the closing boundary.
+func (m *Multipart) write(writer *multipart.Writer) error {
+	for _, part := range m.parts {
+		err := writePart(writer, part)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func writePart(writer *multipart.Writer, part multipartPart) (returnErr error) {
+	content, err := part.open()
+	if err != nil {
+		return fmt.Errorf("failed to open multipart content: %w", err)
+	}
+
+	if closer, ok := content.(io.Closer); ok {
+		defer func() {
+			if cErr := closer.Close(); cErr != nil && returnErr == nil {
+				returnErr = cErr
+			}
+		}()
+	}
+
+	partWriter, err := writer.CreatePart(part.header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(partWriter, content)
+	return err
+}
+
+// progressWriter reports the number of bytes written so far.
+type progressWriter struct {
+	writer     io.Writer
+	onProgress func(written int64)
+	written    int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	if w.onProgress != nil && n > 0 {
+		w.onProgress(w.written)
+	}
+
+	return n, err
+}
+
+func fileHeader(fieldName string, fileName string, contentType string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", formDataDisposition(fieldName, fileName))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+func formDataDisposition(name string, fileName string) string {
+	params := map[string]string{"name": name}
+	if fileName != "" {
+		params["filename"] = fileName
+	}
+
+	return mime.FormatMediaType("form-data", params)
+}
+
+func fileContentType(fileName string) string {
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		return defaultFileContentType
+	}
+
+	return contentType
+}
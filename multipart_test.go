@@ -0,0 +1,113 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipart(t *testing.T) {
+	received := int64(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		atomic.StoreInt64(&received, int64(len(body)))
+
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+		assert.Equal(t, "multipart/form-data", mediaType)
+
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		form, err := reader.ReadForm(1 << 20)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		assert.Equal(t, []string{"someValue"}, form.Value["description"])
+		assert.Equal(t, []string{`{"id":1}`}, form.Value["metadata"])
+
+		files := map[string]string{}
+		for name, headers := range form.File {
+			file, err := headers[0].Open()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadAll(file)
+			assert.NoError(t, err)
+			files[name] = headers[0].Filename + " " + headers[0].Header.Get("Content-Type") + " " + string(content)
+		}
+		assert.Equal(t, map[string]string{
+			"invoice":    "invoice.pdf application/pdf %PDF-1.4",
+			"attachment": "notes.custom text/markdown # Notes",
+			"path":       "upload.bin application/octet-stream file content",
+		}, files)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write([]byte(`{"responseValue":"someValueOut"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	require.NoError(t, ioutil.WriteFile(path, []byte("file content"), 0600))
+
+	t.Run("uploads fields and files", func(t *testing.T) {
+		metadataHeader := textproto.MIMEHeader{}
+		metadataHeader.Set("Content-Disposition", `form-data; name="metadata"`)
+		metadataHeader.Set("Content-Type", "application/json")
+
+		sent := int64(0)
+		body := NewMultipart().
+			AddField("description", "someValue").
+			AddFile("invoice", "invoice.pdf", strings.NewReader("%PDF-1.4")).
+			AddFileWithContentType("attachment", "notes.custom", "text/markdown", strings.NewReader("# Notes")).
+			AddFileFromPath("path", path).
+			AddPart(metadataHeader, strings.NewReader(`{"id":1}`))
+		body.OnProgress = func(written int64) {
+			assert.True(t, written > sent)
+			sent = written
+		}
+
+		result := &Output{}
+		err := Post(ts.URL, body, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+		assert.Equal(t, atomic.LoadInt64(&received), sent)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		discard := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = ioutil.ReadAll(r.Body)
+		}))
+		defer discard.Close()
+
+		body := NewMultipart().AddFileFromPath("path", filepath.Join(t.TempDir(), "missing.bin"))
+		err := Post(discard.URL, body, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open multipart content")
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
+
+func TestMultipartClose(t *testing.T) {
+	body, err := prepareBody(Params{Body: NewMultipart().AddField("a", "b")}, JSONCodec{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(body.contentType, "multipart/form-data; boundary="))
+	assert.False(t, body.replayable())
+
+	require.NoError(t, body.close())
+	_, err = body.reader.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,324 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default names of the query parameters used by the paginators.
+const (
+	defaultCursorParam = "cursor"
+	defaultPageParam   = "page"
+	defaultSizeParam   = "per_page"
+	defaultOffsetParam = "offset"
+	defaultLimitParam  = "limit"
+)
+
+// Page describes a page of a paginated response.
+type Page struct {
+	// Params are the params the page was requested with.
+	Params Params
+	// Response holds the metadata of the response.
+	Response *Response
+	// Body is the raw response body.
+	Body []byte
+	// Number is the zero-based position of the page in the iteration.
+	Number int
+	// Items is the number of items on the page.
+	Items int
+	// Seen is the number of items on this and all previous pages.
+	Seen int
+	// PageSize is the requested number of items per page or zero if none was requested.
+	PageSize int
+}
+
+// Paginator decides how the pages of a paginated endpoint are requested.
+type Paginator interface {
+	// First returns the params for the first page. The page size is zero if the server default should be used.
+	First(params Params, pageSize int) Params
+	// Next returns the params for the page that follows the given one or false if it was the last page.
+	Next(page *Page) (Params, bool, error)
+}
+
+// PaginationSettings configures the iteration over a paginated endpoint.
+type PaginationSettings struct {
+	// Paginator requests the pages. Defaults to LinkPagination.
+	Paginator Paginator
+
+	// ItemsPath is the path of the items array in the JSON response body, e.g. "data" or "result.items".
+	// Elements of arrays can be selected by their index. If it is empty, the body itself must be an array.
+	// A page without the path, without body or with null instead of the array has no items.
+	ItemsPath string
+
+	// PageSize is the number of items that is requested per page. If it is zero, the server default is used.
+	PageSize int
+
+	// MaxPages stops the iteration after that many pages. If it is zero, all pages are requested.
+	MaxPages int
+
+	// Prefetch requests the next page while the items of the current page are consumed.
+	Prefetch bool
+}
+
+// PageIterator yields the items of all pages of a paginated endpoint one at a time.
+// The pages are requested when they are needed. Close must be called if the iteration is stopped early.
+//
+//	it := request.Paginate[Invoice](ctx, params, request.PaginationSettings{ItemsPath: "data"})
+//	defer it.Close()
+//	for it.Next() {
+//		invoice := it.Value()
+//	}
+//	return it.Err()
+type PageIterator[T any] struct {
+	pager      *pager[T]
+	cancel     context.CancelFunc
+	params     Params
+	hasNext    bool
+	page       *Page
+	items      []T
+	index      int
+	value      T
+	err        error
+	closed     bool
+	prefetched chan pageResult[T]
+}
+
+// pager requests single pages. It is not changed during the iteration, so it can be used by the prefetching goroutine.
+type pager[T any] struct {
+	ctx      context.Context
+	client   *Client
+	settings PaginationSettings
+}
+
+type pageResult[T any] struct {
+	page    *Page
+	items   []T
+	next    Params
+	hasNext bool
+	err     error
+}
+
+// Paginate returns an iterator over the items of all pages using the default client.
+func Paginate[T any](ctx context.Context, params Params, settings PaginationSettings) *PageIterator[T] {
+	return PaginateWithClient[T](ctx, getDefaultClient(), params, settings)
+}
+
+// PaginateWithClient is the same as Paginate but uses the provided client.
+func PaginateWithClient[T any](ctx context.Context, client *Client, params Params, settings PaginationSettings) *PageIterator[T] {
+	if settings.Paginator == nil {
+		settings.Paginator = LinkPagination{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &PageIterator[T]{
+		pager:   &pager[T]{ctx: ctx, client: client, settings: settings},
+		cancel:  cancel,
+		params:  settings.Paginator.First(params, settings.PageSize),
+		hasNext: true,
+	}
+}
+
+// Next advances to the next item and requests the next page if needed. It returns false once all pages were
+// read, the page limit was reached or an error occurred. The iterator is closed in all of these cases.
+func (it *PageIterator[T]) Next() bool {
+	for !it.closed && it.index >= len(it.items) {
+		if !it.advance() {
+			it.Close()
+		}
+	}
+
+	if it.closed {
+		return false
+	}
+
+	it.value = it.items[it.index]
+	it.index++
+	return true
+}
+
+// Value returns the current item.
+func (it *PageIterator[T]) Value() T {
+	return it.value
+}
+
+// Page returns the page of the current item.
+func (it *PageIterator[T]) Page() *Page {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *PageIterator[T]) Err() error {
+	return it.err
+}
+
+// Close stops the iteration and cancels a prefetch that is in progress. It can be called more than once.
+func (it *PageIterator[T]) Close() {
+	it.closed = true
+	it.cancel()
+}
+
+// advance moves to the next page. It returns false if there is none or it could not be requested.
+func (it *PageIterator[T]) advance() bool {
+	if !it.hasMorePages() {
+		return false
+	}
+
+	result := it.nextResult()
+	if result.err != nil {
+		it.err = result.err
+		return false
+	}
+
+	it.page, it.items, it.index = result.page, result.items, 0
+	it.params, it.hasNext = result.next, result.hasNext
+	it.prefetch()
+	return true
+}
+
+// hasMorePages checks if there is another page that may be requested.
+func (it *PageIterator[T]) hasMorePages() bool {
+	fetched := 0
+	if it.page != nil {
+		fetched = it.page.Number + 1
+	}
+
+	maxPages := it.pager.settings.MaxPages
+	return it.hasNext && (maxPages == 0 || fetched < maxPages)
+}
+
+// nextResult returns the prefetched page or requests it.
+func (it *PageIterator[T]) nextResult() pageResult[T] {
+	if it.prefetched != nil {
+		result := <-it.prefetched
+		it.prefetched = nil
+		return result
+	}
+
+	return it.pager.fetch(it.params, it.previous())
+}
+
+// prefetch starts requesting the next page in the background.
+func (it *PageIterator[T]) prefetch() {
+	if !it.pager.settings.Prefetch || !it.hasMorePages() {
+		return
+	}
+
+	prefetched := make(chan pageResult[T], 1)
+	pager, params, previous := it.pager, it.params, it.previous()
+	go func() {
+		prefetched <- pager.fetch(params, previous)
+	}()
+	it.prefetched = prefetched
+}
+
+// previous returns a copy of the current page, which is nil before the first page was requested.
+func (it *PageIterator[T]) previous() *Page {
+	if it.page == nil {
+		return nil
+	}
+
+	page := *it.page
+	return &page
+}
+
+// fetch requests the page and decodes its items.
+func (p *pager[T]) fetch(params Params, previous *Page) pageResult[T] {
+	page := &Page{Params: params, PageSize: p.settings.PageSize}
+	if previous != nil {
+		page.Number = previous.Number + 1
+		page.Seen = previous.Seen
+	}
+
+	params.RetainResponseBody = true
+	res, err := p.client.do(p.ctx, params, nil)
+	if err != nil {
+		return pageResult[T]{err: err}
+	}
+
+	items := []T{}
+	raw, ok := lookupJSON(res.RawBody, p.settings.ItemsPath)
+	if ok && !isEmptyJSON(raw) {
+		err = json.Unmarshal(raw, &items)
+		if err != nil {
+			return pageResult[T]{err: fmt.Errorf("failed to decode items of page %d: %w", page.Number, err)}
+		}
+	}
+
+	page.Response, page.Body = res, res.RawBody
+	page.Items = len(items)
+	page.Seen += len(items)
+
+	next, hasNext, err := p.settings.Paginator.Next(page)
+	if err != nil {
+		return pageResult[T]{err: fmt.Errorf("failed to determine the page after page %d: %w", page.Number, err)}
+	}
+
+	return pageResult[T]{page: page, items: items, next: next, hasNext: hasNext}
+}
+
+// lookupJSON returns the value at the dot-separated path. Numeric segments select elements of arrays.
+func lookupJSON(data []byte, path string) (json.RawMessage, bool) {
+	current := json.RawMessage(data)
+	if path == "" {
+		return current, true
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		var ok bool
+		current, ok = lookupJSONSegment(current, segment)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func lookupJSONSegment(data json.RawMessage, segment string) (json.RawMessage, bool) {
+	if index, err := strconv.Atoi(segment); err == nil {
+		elements := []json.RawMessage{}
+		if json.Unmarshal(data, &elements) != nil || index < 0 || index >= len(elements) {
+			return nil, false
+		}
+		return elements[index], true
+	}
+
+	members := map[string]json.RawMessage{}
+	if json.Unmarshal(data, &members) != nil {
+		return nil, false
+	}
+
+	value, ok := members[segment]
+	return value, ok
+}
+
+// isEmptyJSON checks if the value is null or missing completely, e.g. because the response had no body.
+func isEmptyJSON(data json.RawMessage) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) == 0 || bytes.Equal(data, []byte("null"))
+}
+
+// withQuery returns a copy of the params with the query parameter set.
+func withQuery(params Params, key string, value string) Params {
+	query := map[string]string{}
+	for k, v := range params.Query {
+		query[k] = v
+	}
+
+	query[key] = value
+	params.Query = query
+	return params
+}
+
+// withPageSize sets the page size as query parameter if it is specified.
+func withPageSize(params Params, sizeParam string, pageSize int) Params {
+	if pageSize <= 0 {
+		return params
+	}
+
+	return withQuery(params, sizeParam, strconv.Itoa(pageSize))
+}
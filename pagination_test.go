@@ -0,0 +1,205 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type paginatedItem struct {
+	ID int `json:"id"`
+}
+
+// newPaginatedServer serves 10 items with the different pagination styles. It counts the requests.
+func newPaginatedServer(calls *int32) *httptest.Server {
+	items := []paginatedItem{}
+	for i := 1; i <= 10; i++ {
+		items = append(items, paginatedItem{ID: i})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		query := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(query.Get("page"))
+			size, _ := strconv.Atoi(query.Get("per_page"))
+			if size == 0 {
+				size = 3
+			}
+			start, end := window(page*size, size, len(items))
+			if end < len(items) {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d&per_page=%d>; rel="next", </link?page=0>; rel="first"`, page+1, size))
+			}
+			_ = json.NewEncoder(w).Encode(items[start:end])
+		case "/cursor":
+			offset, _ := strconv.Atoi(query.Get("cursor"))
+			size, _ := strconv.Atoi(query.Get("limit"))
+			start, end := window(offset, size, len(items))
+			body := map[string]interface{}{"data": items[start:end], "meta": map[string]interface{}{"next_cursor": nil}}
+			if end < len(items) {
+				body["meta"] = map[string]interface{}{"next_cursor": strconv.Itoa(end)}
+			}
+			_ = json.NewEncoder(w).Encode(body)
+		case "/page":
+			page, _ := strconv.Atoi(query.Get("page"))
+			size, _ := strconv.Atoi(query.Get("per_page"))
+			if size == 0 {
+				size = 3
+			}
+			start, end := window((page-1)*size, size, len(items))
+			_ = json.NewEncoder(w).Encode(items[start:end])
+		case "/offset":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			size, _ := strconv.Atoi(query.Get("limit"))
+			start, end := window(offset, size, len(items))
+			_ = json.NewEncoder(w).Encode(items[start:end])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// window returns the bounds of the slice of the items on a page. The default page size is 3.
+func window(start int, size int, total int) (int, int) {
+	if size == 0 {
+		size = 3
+	}
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func collectPages[T any](it *PageIterator[T]) []T {
+	result := []T{}
+	for it.Next() {
+		result = append(result, it.Value())
+	}
+	return result
+}
+
+func TestPaginate(t *testing.T) {
+	calls := int32(0)
+	ts := newPaginatedServer(&calls)
+	defer ts.Close()
+	ctx := context.Background()
+
+	all := []paginatedItem{}
+	for i := 1; i <= 10; i++ {
+		all = append(all, paginatedItem{ID: i})
+	}
+
+	tests := map[string]struct {
+		path     string
+		settings PaginationSettings
+		requests int32
+	}{
+		"link":             {"/link", PaginationSettings{PageSize: 4}, 3},
+		"link default":     {"/link", PaginationSettings{}, 4},
+		"cursor":           {"/cursor", PaginationSettings{Paginator: CursorPagination{CursorPath: "meta.next_cursor"}, ItemsPath: "data", PageSize: 5}, 2},
+		"page":             {"/page", PaginationSettings{Paginator: PagePagination{}, PageSize: 3}, 4},
+		"page total count": {"/page", PaginationSettings{Paginator: PagePagination{TotalCountHeader: "X-Total-Count"}, PageSize: 5}, 2},
+		"page empty":       {"/page", PaginationSettings{Paginator: PagePagination{}}, 5},
+		"offset":           {"/offset", PaginationSettings{Paginator: OffsetPagination{}, PageSize: 5}, 3},
+		"offset prefetch":  {"/offset", PaginationSettings{Paginator: OffsetPagination{TotalCountHeader: "X-Total-Count"}, PageSize: 5, Prefetch: true}, 2},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			it := Paginate[paginatedItem](ctx, Params{URL: ts.URL + test.path}, test.settings)
+			defer it.Close()
+
+			assert.Equal(t, all, collectPages(it))
+			assert.NoError(t, it.Err())
+			assert.Equal(t, test.requests, atomic.LoadInt32(&calls))
+			assert.False(t, it.Next())
+		})
+	}
+
+	t.Run("max pages", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		it := PaginateWithClient[paginatedItem](ctx, NewClient(), Params{URL: ts.URL + "/link"}, PaginationSettings{MaxPages: 2, Prefetch: true})
+		assert.Equal(t, all[:6], collectPages(it))
+		assert.NoError(t, it.Err())
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("page metadata", func(t *testing.T) {
+		it := Paginate[paginatedItem](ctx, Params{URL: ts.URL + "/offset"}, PaginationSettings{Paginator: OffsetPagination{}, PageSize: 4})
+		defer it.Close()
+		for i := 0; i < 5; i++ {
+			require.True(t, it.Next())
+		}
+		page := it.Page()
+		assert.Equal(t, 1, page.Number)
+		assert.Equal(t, 4, page.Items)
+		assert.Equal(t, 8, page.Seen)
+		assert.Equal(t, "4", page.Params.Query["offset"])
+		assert.Equal(t, "10", page.Response.Header.Get("X-Total-Count"))
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		it := Paginate[paginatedItem](ctx, Params{URL: ts.URL + "/missing"}, PaginationSettings{})
+		assert.False(t, it.Next())
+		responseErr := &Error{}
+		require.True(t, errors.As(it.Err(), &responseErr))
+		assert.Equal(t, http.StatusNotFound, responseErr.StatusCode)
+	})
+
+	t.Run("invalid items", func(t *testing.T) {
+		it := Paginate[paginatedItem](ctx, Params{URL: ts.URL + "/cursor"}, PaginationSettings{Paginator: CursorPagination{CursorPath: "meta.next_cursor"}})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), "failed to decode items of page 0: json: cannot unmarshal object into Go value of type []request.paginatedItem")
+	})
+
+	t.Run("repeated cursor", func(t *testing.T) {
+		repeated := int32(0)
+		repeating := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&repeated, 1)
+			_, _ = w.Write([]byte(`{"data":[{"id":1}],"next":"same"}`))
+		}))
+		defer repeating.Close()
+
+		it := Paginate[paginatedItem](ctx, Params{URL: repeating.URL}, PaginationSettings{Paginator: CursorPagination{CursorPath: "next"}, ItemsPath: "data"})
+		assert.Len(t, collectPages(it), 1)
+		assert.EqualError(t, it.Err(), `failed to determine the page after page 1: the server returned the cursor "same" again`)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&repeated))
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		cancelCtx, cancel := context.WithCancel(ctx)
+		it := Paginate[paginatedItem](cancelCtx, Params{URL: ts.URL + "/link"}, PaginationSettings{})
+		require.True(t, it.Next())
+		cancel()
+
+		assert.Len(t, collectPages(it), 2)
+		assert.True(t, errors.Is(it.Err(), context.Canceled))
+	})
+
+	t.Run("closed early", func(t *testing.T) {
+		it := Paginate[paginatedItem](ctx, Params{URL: ts.URL + "/link"}, PaginationSettings{Prefetch: true})
+		require.True(t, it.Next())
+		it.Close()
+		it.Close()
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+	})
+}
@@ -0,0 +1,267 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// LinkPagination follows the Link header with the relation type "next" as defined in RFC 8288.
+// The iteration ends with the first response that has no such link.
+type LinkPagination struct {
+	// SizeParam is the query parameter the page size is sent in. Defaults to "per_page".
+	SizeParam string
+}
+
+// First sets the page size.
+func (p LinkPagination) First(params Params, pageSize int) Params {
+	return withPageSize(params, keyOrDefault(p.SizeParam, defaultSizeParam), pageSize)
+}
+
+// Next returns the params with the URL of the next link, which already contains the query.
+func (p LinkPagination) Next(page *Page) (Params, bool, error) {
+	target, ok := nextLink(page.Response.Header)
+	if !ok {
+		return Params{}, false, nil
+	}
+
+	base := page.Response.URL
+	if base == nil {
+		base = &url.URL{}
+	}
+	next, err := base.Parse(target)
+	if err != nil {
+		return Params{}, false, fmt.Errorf("invalid next link %q: %w", target, err)
+	}
+
+	params := page.Params
+	params.URL = next.String()
+	params.Query = nil
+	return params, true, nil
+}
+
+// CursorPagination reads the cursor of the next page from the JSON response body and sends it as query parameter.
+// The iteration ends once the cursor is missing, null or empty. It fails if the server returns the cursor
+// the page was requested with again.
+type CursorPagination struct {
+	// CursorPath is the path of the cursor in the response body, e.g. "meta.next_cursor".
+	// Elements of arrays can be selected by their index.
+	CursorPath string
+
+	// CursorParam is the query parameter the cursor is sent in. Defaults to "cursor".
+	CursorParam string
+
+	// SizeParam is the query parameter the page size is sent in. Defaults to "limit".
+	SizeParam string
+}
+
+// First sets the page size.
+func (p CursorPagination) First(params Params, pageSize int) Params {
+	return withPageSize(params, keyOrDefault(p.SizeParam, defaultLimitParam), pageSize)
+}
+
+// Next returns the params with the cursor of the page.
+func (p CursorPagination) Next(page *Page) (Params, bool, error) {
+	if p.CursorPath == "" {
+		return Params{}, false, errors.New("the cursor path is not set")
+	}
+
+	raw, ok := lookupJSON(page.Body, p.CursorPath)
+	if !ok || isEmptyJSON(raw) {
+		return Params{}, false, nil
+	}
+
+	cursor, err := jsonScalar(raw)
+	if err != nil || cursor == "" {
+		return Params{}, false, err
+	}
+
+	cursorParam := keyOrDefault(p.CursorParam, defaultCursorParam)
+	if cursor == page.Params.Query[cursorParam] {
+		return Params{}, false, fmt.Errorf("the server returned the cursor %q again", cursor)
+	}
+
+	return withQuery(page.Params, cursorParam, cursor), true, nil
+}
+
+// PagePagination requests the pages by their number. The iteration ends with a page that has fewer items than
+// the page size, no items at all or once the total count of items was reached.
+type PagePagination struct {
+	// PageParam is the query parameter the page number is sent in. Defaults to "page".
+	// If the params already have a page number, the iteration starts there.
+	PageParam string
+
+	// SizeParam is the query parameter the page size is sent in. Defaults to "per_page".
+	SizeParam string
+
+	// ZeroBased numbers the first page 0 instead of 1.
+	ZeroBased bool
+
+	// TotalCountHeader is the response header holding the total number of items, e.g. "X-Total-Count".
+	TotalCountHeader string
+}
+
+// First sets the number of the first page and the page size.
+func (p PagePagination) First(params Params, pageSize int) Params {
+	firstPage := 1
+	if p.ZeroBased {
+		firstPage = 0
+	}
+
+	params = withDefaultQuery(params, keyOrDefault(p.PageParam, defaultPageParam), strconv.Itoa(firstPage))
+	return withPageSize(params, keyOrDefault(p.SizeParam, defaultSizeParam), pageSize)
+}
+
+// Next increments the page number.
+func (p PagePagination) Next(page *Page) (Params, bool, error) {
+	if isLastPage(page, p.TotalCountHeader) {
+		return Params{}, false, nil
+	}
+
+	pageParam := keyOrDefault(p.PageParam, defaultPageParam)
+	number, err := strconv.Atoi(page.Params.Query[pageParam])
+	if err != nil {
+		return Params{}, false, fmt.Errorf("invalid page number: %w", err)
+	}
+
+	return withQuery(page.Params, pageParam, strconv.Itoa(number+1)), true, nil
+}
+
+// OffsetPagination requests the pages by the offset of their first item. The iteration ends with a page that has
+// fewer items than the page size, no items at all or once the total count of items was reached.
+type OffsetPagination struct {
+	// OffsetParam is the query parameter the offset is sent in. Defaults to "offset".
+	// If the params already have an offset, the iteration starts there.
+	OffsetParam string
+
+	// LimitParam is the query parameter the page size is sent in. Defaults to "limit".
+	LimitParam string
+
+	// TotalCountHeader is the response header holding the total number of items, e.g. "X-Total-Count".
+	TotalCountHeader string
+}
+
+// First sets the offset and the page size.
+func (p OffsetPagination) First(params Params, pageSize int) Params {
+	params = withDefaultQuery(params, keyOrDefault(p.OffsetParam, defaultOffsetParam), "0")
+	return withPageSize(params, keyOrDefault(p.LimitParam, defaultLimitParam), pageSize)
+}
+
+// Next advances the offset by the number of items on the page.
+func (p OffsetPagination) Next(page *Page) (Params, bool, error) {
+	if isLastPage(page, p.TotalCountHeader) {
+		return Params{}, false, nil
+	}
+
+	offsetParam := keyOrDefault(p.OffsetParam, defaultOffsetParam)
+	offset, err := strconv.Atoi(page.Params.Query[offsetParam])
+	if err != nil {
+		return Params{}, false, fmt.Errorf("invalid offset: %w", err)
+	}
+
+	return withQuery(page.Params, offsetParam, strconv.Itoa(offset+page.Items)), true, nil
+}
+
+// isLastPage checks if the page is incomplete or the total count of items was reached.
+func isLastPage(page *Page, totalCountHeader string) bool {
+	if page.Items == 0 || (page.PageSize > 0 && page.Items < page.PageSize) {
+		return true
+	}
+
+	if totalCountHeader == "" {
+		return false
+	}
+
+	total, err := strconv.Atoi(strings.TrimSpace(page.Response.Header.Get(totalCountHeader)))
+	return err == nil && page.Seen >= total
+}
+
+// withDefaultQuery sets the query parameter unless the params already have it.
+func withDefaultQuery(params Params, key string, value string) Params {
+	if _, ok := params.Query[key]; ok {
+		return params
+	}
+
+	return withQuery(params, key, value)
+}
+
+// jsonScalar returns a JSON string or number as string.
+func jsonScalar(raw json.RawMessage) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("expected cursor to be a string or number but got %s", raw)
+	}
+}
+
+// nextLink returns the target of the first link with the relation type "next".
+func nextLink(header http.Header) (string, bool) {
+	for _, value := range header.Values("Link") {
+		for _, link := range splitLinkHeader(value, ',') {
+			target, relations := parseLink(link)
+			for _, relation := range relations {
+				if relation == "next" {
+					return target, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// parseLink returns the target and the relation types of a single link.
+func parseLink(link string) (string, []string) {
+	parts := splitLinkHeader(link, ';')
+	target := strings.TrimSpace(parts[0])
+	if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+		return "", nil
+	}
+
+	relations := []string{}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(strings.TrimSpace(name), "rel") {
+			relations = strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`)))
+		}
+	}
+
+	return target[1 : len(target)-1], relations
+}
+
+// splitLinkHeader splits the value at the separator unless it is part of a quoted string or a URL in angle brackets.
+func splitLinkHeader(value string, separator rune) []string {
+	parts := []string{}
+	start := 0
+	quoted, bracketed := false, false
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '<' || r == '>':
+			bracketed = r == '<'
+		case r == separator && !quoted && !bracketed:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, value[start:])
+}
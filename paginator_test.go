@@ -0,0 +1,125 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]struct {
+		header   []string
+		expected string
+		ok       bool
+	}{
+		"single":              {[]string{`<https://example.com/items?page=2>; rel="next"`}, "https://example.com/items?page=2", true},
+		"multiple relations":  {[]string{`<https://example.com/items?page=1>; rel="prev", <https://example.com/items?page=3>; rel="next last"`}, "https://example.com/items?page=3", true},
+		"multiple headers":    {[]string{`</first>; rel=first`, `</next>; title="a; b, c"; REL=Next`}, "/next", true},
+		"comma in url":        {[]string{`</items?ids=1,2>; rel="next"`}, "/items?ids=1,2", true},
+		"no next":             {[]string{`</first>; rel="first"`}, "", false},
+		"missing header":      {nil, "", false},
+		"invalid link":        {[]string{`/next; rel="next"`}, "", false},
+		"relation in a title": {[]string{`</other>; title="rel=next"`}, "", false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, ok := nextLink(http.Header{"Link": test.header})
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, target)
+		})
+	}
+}
+
+func TestLinkPagination(t *testing.T) {
+	requested, _ := url.Parse("https://example.com/api/items?page=1")
+	page := &Page{
+		Params:   Params{URL: "https://example.com/api/items", Query: map[string]string{"page": "1"}, Headers: map[string]string{"Authorization": "token"}},
+		Response: &Response{URL: requested, Header: http.Header{"Link": []string{`<items?page=2>; rel="next"`}}},
+	}
+
+	params, ok, err := LinkPagination{}.Next(page)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/api/items?page=2", params.URL)
+	assert.Nil(t, params.Query)
+	assert.Equal(t, "token", params.Headers["Authorization"])
+
+	params = LinkPagination{SizeParam: "size"}.First(Params{}, 50)
+	assert.Equal(t, map[string]string{"size": "50"}, params.Query)
+}
+
+func TestCursorPagination(t *testing.T) {
+	paginator := CursorPagination{CursorPath: "pages.0.next"}
+	tests := map[string]struct {
+		body     string
+		expected string
+		ok       bool
+	}{
+		"string":  {`{"pages":[{"next":"abc"}]}`, "abc", true},
+		"number":  {`{"pages":[{"next":12345678901234567890}]}`, "12345678901234567890", true},
+		"null":    {`{"pages":[{"next":null}]}`, "", false},
+		"empty":   {`{"pages":[{"next":""}]}`, "", false},
+		"missing": {`{"pages":[]}`, "", false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			page := &Page{Params: Params{Query: map[string]string{"limit": "10"}}, Body: []byte(test.body)}
+			params, ok, err := paginator.Next(page)
+			require.NoError(t, err)
+			assert.Equal(t, test.ok, ok)
+			if ok {
+				assert.Equal(t, map[string]string{"limit": "10", "cursor": test.expected}, params.Query)
+			}
+		})
+	}
+
+	_, _, err := paginator.Next(&Page{Body: []byte(`{"pages":[{"next":{}}]}`)})
+	assert.EqualError(t, err, "expected cursor to be a string or number but got {}")
+
+	_, _, err = CursorPagination{}.Next(&Page{Body: []byte(`{}`)})
+	assert.EqualError(t, err, "the cursor path is not set")
+}
+
+func TestPagePagination(t *testing.T) {
+	params := PagePagination{ZeroBased: true}.First(Params{}, 20)
+	assert.Equal(t, map[string]string{"page": "0", "per_page": "20"}, params.Query)
+
+	params = PagePagination{PageParam: "p"}.First(Params{Query: map[string]string{"p": "5"}}, 0)
+	assert.Equal(t, map[string]string{"p": "5"}, params.Query)
+
+	page := &Page{Params: params, Items: 20, Response: &Response{Header: http.Header{}}}
+	params, ok, err := PagePagination{PageParam: "p"}.Next(page)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"p": "6"}, params.Query)
+
+	page.Params.Query = map[string]string{"p": "x"}
+	_, _, err = PagePagination{PageParam: "p"}.Next(page)
+	assert.Error(t, err)
+}
+
+func TestOffsetPagination(t *testing.T) {
+	paginator := OffsetPagination{TotalCountHeader: "X-Total"}
+	params := paginator.First(Params{}, 10)
+	assert.Equal(t, map[string]string{"offset": "0", "limit": "10"}, params.Query)
+
+	page := &Page{Params: params, Items: 10, Seen: 10, PageSize: 10, Response: &Response{Header: http.Header{"X-Total": []string{"25"}}}}
+	params, ok, err := paginator.Next(page)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "10", params.Query["offset"])
+
+	page.Seen = 25
+	_, ok, err = paginator.Next(page)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	page.Seen, page.Items = 15, 5
+	_, ok, _ = paginator.Next(page)
+	assert.False(t, ok)
+}
@@ -0,0 +1,105 @@
+package request
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// ProblemContentType is the media type of problem details as defined in RFC 9457.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails describes an error as defined in RFC 9457 (formerly RFC 7807).
+type ProblemDetails struct {
+	// Type is a URI reference that identifies the problem type.
+	Type string
+	// Title is a short, human-readable summary of the problem type.
+	Title string
+	// Status is the HTTP status code set by the server.
+	Status int
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string
+	// Extensions holds all additional members of the problem details object.
+	Extensions map[string]interface{}
+}
+
+// problemMembers are the members of the problem details object that are defined by RFC 9457.
+type problemMembers struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// UnmarshalJSON decodes the standard members into the fields and all other members into Extensions.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	members := problemMembers{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	extensions := map[string]interface{}{}
+	if err := json.Unmarshal(data, &extensions); err != nil {
+		return err
+	}
+	for _, name := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(extensions, name)
+	}
+
+	*p = ProblemDetails{
+		Type:       members.Type,
+		Title:      members.Title,
+		Status:     members.Status,
+		Detail:     members.Detail,
+		Instance:   members.Instance,
+		Extensions: extensions,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the problem details as a single object with the extensions as additional members.
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	result := map[string]interface{}{}
+	for name, value := range p.Extensions {
+		result[name] = value
+	}
+
+	members, err := json.Marshal(problemMembers{Type: p.Type, Title: p.Title, Status: p.Status, Detail: p.Detail, Instance: p.Instance})
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(members, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(result)
+}
+
+// AsProblem returns the problem details of the error response if the server sent them.
+func AsProblem(err error) (*ProblemDetails, bool) {
+	responseErr := asError(err)
+	if responseErr == nil || responseErr.Problem == nil {
+		return nil, false
+	}
+
+	return responseErr.Problem, true
+}
+
+// parseProblem returns the problem details if the response has the problem+json content type.
+func parseProblem(header http.Header, body []byte) *ProblemDetails {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != ProblemContentType {
+		return nil
+	}
+
+	problem := &ProblemDetails{}
+	if err := json.Unmarshal(body, problem); err != nil {
+		return nil
+	}
+
+	return problem
+}
@@ -0,0 +1,74 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemDetails(t *testing.T) {
+	t.Run("parses problem responses", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Contains(t, r.Header.Get("Accept"), ProblemContentType)
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			_, err := w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",` +
+				`"status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		err := Do(Params{URL: ts.URL}, nil)
+		problem, ok := AsProblem(err)
+		require.True(t, ok)
+		assert.Equal(t, "https://example.com/probs/out-of-credit", problem.Type)
+		assert.Equal(t, "You do not have enough credit.", problem.Title)
+		assert.Equal(t, http.StatusForbidden, problem.Status)
+		assert.Equal(t, "Your current balance is 30, but that costs 50.", problem.Detail)
+		assert.Equal(t, "/account/12345/msgs/abc", problem.Instance)
+		assert.Equal(t, map[string]interface{}{"balance": float64(30)}, problem.Extensions)
+
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Same(t, problem, responseErr.Problem)
+	})
+
+	t.Run("ignores other content types", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, err := w.Write([]byte(`{"title":"Bad Request"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		err := Do(Params{URL: ts.URL}, nil)
+		assert.Error(t, err)
+		_, ok := AsProblem(err)
+		assert.False(t, ok)
+		_, ok = AsProblem(errors.New("some error"))
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid problem body", func(t *testing.T) {
+		header := http.Header{"Content-Type": []string{ProblemContentType}}
+		assert.Nil(t, parseProblem(header, []byte(`{"status":"not a number"}`)))
+		assert.Nil(t, parseProblem(header, []byte(`not json`)))
+	})
+
+	t.Run("marshals extensions as members", func(t *testing.T) {
+		problem := ProblemDetails{Title: "Not Found", Status: http.StatusNotFound, Extensions: map[string]interface{}{"id": "abc"}}
+		data, err := json.Marshal(problem)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"title":"Not Found","status":404,"id":"abc"}`, string(data))
+
+		decoded := ProblemDetails{}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, problem, decoded)
+	})
+}
@@ -0,0 +1,170 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned if a request was not sent because the rate limit would be exceeded.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// unixTimestampThreshold separates reset values given as unix timestamp from values given in seconds.
+const unixTimestampThreshold = 1000000000
+
+// RateLimit holds the rate limiting information the server sent with a response.
+type RateLimit struct {
+	// RetryAfter is the time to wait before the next request as given in the Retry-After header.
+	// It is zero if the header was not present.
+	RetryAfter time.Duration
+	// Limit is the value of the X-RateLimit-Limit header or -1 if it was not present.
+	Limit int
+	// Remaining is the value of the X-RateLimit-Remaining header or -1 if it was not present.
+	Remaining int
+	// Reset is the time the rate limit is reset as given in the X-RateLimit-Reset header.
+	// It is the zero time if the header was not present.
+	Reset time.Time
+}
+
+// RateLimitError is returned for responses with status code 429 or 503.
+// It wraps the HTTPError and adds the rate limiting information of the response.
+type RateLimitError struct {
+	Err       error
+	RateLimit RateLimit
+}
+
+// Error returns the message of the wrapped error.
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped HTTPError.
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// isRateLimitStatus reports whether the status code indicates the server is rate limiting or overloaded.
+func isRateLimitStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRateLimit reads the Retry-After and X-RateLimit-* headers.
+func parseRateLimit(header http.Header, now time.Time) RateLimit {
+	return RateLimit{
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), now),
+		Limit:      parseHeaderInt(header.Get("X-RateLimit-Limit")),
+		Remaining:  parseHeaderInt(header.Get("X-RateLimit-Remaining")),
+		Reset:      parseRateLimitReset(header.Get("X-RateLimit-Reset"), now),
+	}
+}
+
+// parseRetryAfter supports both forms of the Retry-After header, delta-seconds and HTTP-date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+
+	return date.Sub(now)
+}
+
+// parseRateLimitReset accepts a unix timestamp or the number of seconds until the reset.
+func parseRateLimitReset(value string, now time.Time) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}
+	}
+
+	if seconds >= unixTimestampThreshold {
+		return time.Unix(seconds, 0)
+	}
+
+	return now.Add(time.Duration(seconds) * time.Second)
+}
+
+func parseHeaderInt(value string) int {
+	result, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1
+	}
+
+	return result
+}
+
+// RateLimitTracker keeps track of the rate limits announced by the servers per host.
+// If a client uses a tracker, requests to a host are delayed until the rate limit of
+// the host is reset. A tracker can be shared between clients.
+type RateLimitTracker struct {
+	mutex sync.Mutex
+	hosts map[string]time.Time
+}
+
+// NewRateLimitTracker returns an empty tracker.
+func NewRateLimitTracker() *RateLimitTracker {
+	return &RateLimitTracker{hosts: map[string]time.Time{}}
+}
+
+// BlockedUntil returns the time until which requests to the host are delayed.
+// It returns the zero time if the host is not rate limited.
+func (t *RateLimitTracker) BlockedUntil(host string) time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.hosts[host]
+}
+
+// record updates the state of the host with the rate limit information of the response.
+func (t *RateLimitTracker) record(host string, statusCode int, rateLimit RateLimit, now time.Time) {
+	until := time.Time{}
+	if rateLimit.Remaining == 0 && rateLimit.Reset.After(now) {
+		until = rateLimit.Reset
+	}
+	if isRateLimitStatus(statusCode) && rateLimit.RetryAfter > 0 {
+		until = now.Add(rateLimit.RetryAfter)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if until.IsZero() {
+		if !t.hosts[host].After(now) {
+			delete(t.hosts, host)
+		}
+		return
+	}
+
+	if until.After(t.hosts[host]) {
+		t.hosts[host] = until
+	}
+}
+
+// wait blocks until the rate limit of the host is reset. If that does not happen
+// before the deadline of the context, ErrRateLimited is returned right away.
+func (t *RateLimitTracker) wait(ctx context.Context, host string) error {
+	until := t.BlockedUntil(host)
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(until) {
+		return fmt.Errorf("%w: host %s is rate limited until %s", ErrRateLimited, host, until.Format(time.RFC3339))
+	}
+
+	return sleep(ctx, delay)
+}
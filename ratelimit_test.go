@@ -0,0 +1,170 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fastbill/go-httperrors/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("delta seconds", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Retry-After", "120")
+		header.Set("X-RateLimit-Limit", "100")
+		header.Set("X-RateLimit-Remaining", "0")
+		header.Set("X-RateLimit-Reset", "30")
+
+		rateLimit := parseRateLimit(header, now)
+		assert.Equal(t, 2*time.Minute, rateLimit.RetryAfter)
+		assert.Equal(t, 100, rateLimit.Limit)
+		assert.Equal(t, 0, rateLimit.Remaining)
+		assert.Equal(t, now.Add(30*time.Second), rateLimit.Reset)
+	})
+
+	t.Run("http date and unix timestamp", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Retry-After", now.Add(90*time.Second).Format(http.TimeFormat))
+		header.Set("X-RateLimit-Reset", "1654089000")
+
+		rateLimit := parseRateLimit(header, now)
+		assert.Equal(t, 90*time.Second, rateLimit.RetryAfter)
+		assert.Equal(t, time.Unix(1654089000, 0), rateLimit.Reset)
+	})
+
+	t.Run("missing or invalid headers", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Retry-After", "soon")
+
+		rateLimit := parseRateLimit(header, now)
+		assert.Equal(t, time.Duration(0), rateLimit.RetryAfter)
+		assert.Equal(t, -1, rateLimit.Limit)
+		assert.Equal(t, -1, rateLimit.Remaining)
+		assert.True(t, rateLimit.Reset.IsZero())
+	})
+
+	t.Run("date in the past", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-time.Hour).Format(http.TimeFormat), now))
+	})
+}
+
+func TestRateLimitError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	err := Get(ts.URL, nil)
+	rateLimitErr := &RateLimitError{}
+	require.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 3*time.Second, rateLimitErr.RateLimit.RetryAfter)
+	assert.Equal(t, 0, rateLimitErr.RateLimit.Remaining)
+
+	httpErr := &httperrors.HTTPError{}
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+}
+
+func TestHonorRetryAfter(t *testing.T) {
+	t.Run("waits and retries", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer ts.Close()
+
+		delays := []time.Duration{}
+		params := Params{
+			URL: ts.URL,
+			Retry: &RetryPolicy{
+				HonorRetryAfter: true,
+				OnRetry: func(attempt int, err error, delay time.Duration) {
+					delays = append(delays, delay)
+				},
+			},
+		}
+
+		start := time.Now()
+		err := Do(params, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		assert.Equal(t, []time.Duration{time.Second}, delays)
+		assert.True(t, time.Since(start) >= time.Second)
+	})
+
+	t.Run("does not wait beyond the deadline", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		params := Params{
+			URL:   ts.URL,
+			Retry: &RetryPolicy{HonorRetryAfter: true},
+		}
+
+		start := time.Now()
+		err := DoContext(ctx, params, nil)
+		assert.True(t, errors.As(err, new(*RateLimitError)))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+}
+
+func TestRateLimitTracker(t *testing.T) {
+	calls := int32(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "1")
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	client := NewClient()
+	client.RateLimitTracker = NewRateLimitTracker()
+
+	err = client.Get(context.Background(), ts.URL, nil)
+	require.NoError(t, err)
+	assert.True(t, client.RateLimitTracker.BlockedUntil(u.Host).After(time.Now()))
+
+	t.Run("fails fast if the deadline is too short", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := client.Get(ctx, ts.URL, nil)
+		assert.True(t, errors.Is(err, ErrRateLimited))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("waits until the reset", func(t *testing.T) {
+		err := client.Get(context.Background(), ts.URL, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		assert.True(t, client.RateLimitTracker.BlockedUntil(u.Host).IsZero())
+	})
+}
@@ -1,46 +1,34 @@
 package request
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
-
-	"github.com/fastbill/go-httperrors/v2"
 )
 
-// cachedClient is the global client instance.
-var cachedClient *http.Client
-
 // defaultTimeout is the timeout applied if there is none provided.
 var defaultTimeout = 30 * time.Second
 
-// getCachedClient returns the client instance or creates it if it did not exist.
-// The client does not follow redirects and has a timeout of defaultTimeout.
-func getCachedClient() *http.Client {
-	if cachedClient == nil {
-		cachedClient = GetClient()
-	}
-
-	return cachedClient
-}
-
 // GetClient returns an http client that does not follow redirects and has a timeout of defaultTimeout.
+// All clients returned by GetClient share the same transport.
 func GetClient() *http.Client {
 	return &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Timeout: defaultTimeout,
+		CheckRedirect: noRedirect,
+		Transport:     sharedTransport,
+		Timeout:       defaultTimeout,
 	}
 }
 
+// noRedirect makes the http client return the redirect response instead of following it.
+func noRedirect(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
 // Params holds all information necessary to set up the request instance.
+// Timeout limits the duration of the request. If the context has an earlier deadline, that one applies.
+// Without a timeout in the params, the timeout of the client is used unless the context has a deadline.
 type Params struct {
 	URL                  string
 	Method               string
@@ -49,147 +37,92 @@ type Params struct {
 	Query                map[string]string
 	Timeout              time.Duration
 	ExpectedResponseCode int
+	Retry                *RetryPolicy
+	BodyReplay           BodyReplay
+	MaxBodyBufferBytes   int64
+	CircuitBreakerKey    string
+	RateLimiter          *RateLimiter
+	RateLimitKey         string
+	Middlewares          []Middleware
+	RetainResponseBody   bool
+	ErrorBody            interface{}
+	ErrorBodies          ErrorBodies
+	Codec                Codec
+	MaxResponseBytes     int64
+	MaxErrorBodyBytes    int64
+	OnDownloadProgress   func(received int64, total int64)
+	Compression          *Compression
 }
 
 // Do executes the request as specified in the request params.
 // The response body will be parsed into the provided struct.
 // Optionally, the headers will be copied if a header map was provided.
-func Do(params Params, responseBody interface{}, responseHeaderArg ...http.Header) (returnErr error) {
-	req, err := createRequest(params)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	client := selectClient(params.Timeout)
-	res, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	defer func() {
-		if cErr := res.Body.Close(); cErr != nil && returnErr == nil {
-			returnErr = cErr
-		}
-	}()
-
-	err = checkResponseCode(res, params.ExpectedResponseCode)
-	if err != nil {
-		return err
-	}
-
-	err = populateResponseHeader(res, responseHeaderArg)
-	if err != nil {
-		return err
-	}
-
-	if responseBody == nil {
-		return nil
-	}
+func Do(params Params, responseBody interface{}, responseHeaderArg ...http.Header) error {
+	return DoContext(context.Background(), params, responseBody, responseHeaderArg...)
+}
 
-	return json.NewDecoder(res.Body).Decode(responseBody)
+// DoContext is the same as Do but the request is bound to the provided context.
+// If the context has a deadline, it takes priority over the configured timeouts.
+func DoContext(ctx context.Context, params Params, responseBody interface{}, responseHeaderArg ...http.Header) error {
+	return getDefaultClient().Do(ctx, params, responseBody, responseHeaderArg...)
 }
 
 // DoWithStringResponse is the same as Do but the response body is returned as string
 // instead of being parsed into the provided struct.
-func DoWithStringResponse(params Params) (result string, returnErr error) {
-	req, err := createRequest(params)
-	if err != nil {
-		return "", err
-	}
-
-	client := selectClient(params.Timeout)
-	res, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-
-	defer func() {
-		if cErr := res.Body.Close(); cErr != nil && returnErr == nil {
-			returnErr = cErr
-		}
-	}()
-
-	err = checkResponseCode(res, params.ExpectedResponseCode)
-	if err != nil {
-		return "", err
-	}
-
-	bodyBytes, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
+func DoWithStringResponse(params Params) (string, error) {
+	return DoWithStringResponseContext(context.Background(), params)
+}
 
-	return string(bodyBytes), nil
+// DoWithStringResponseContext is the same as DoWithStringResponse but the request
+// is bound to the provided context.
+func DoWithStringResponseContext(ctx context.Context, params Params) (string, error) {
+	return getDefaultClient().DoWithStringResponse(ctx, params)
 }
 
 // DoWithCustomClient is the same as Do but will make the request using the
-// supplied http.Client instead of the cachedClient.
+// supplied http.Client instead of the one of the default client.
 // TODO client should become the first parameter in the next major update
-// so we can add the response headers at the end. They are currently not supported.
-func DoWithCustomClient(params Params, responseBody interface{}, client *http.Client) (returnErr error) {
-	req, err := createRequest(params)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	res, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	defer func() {
-		if cErr := res.Body.Close(); cErr != nil && returnErr == nil {
-			returnErr = cErr
-		}
-	}()
-
-	err = checkResponseCode(res, params.ExpectedResponseCode)
-	if err != nil {
-		return err
-	}
-
-	if responseBody == nil {
-		return nil
-	}
-
-	return json.NewDecoder(res.Body).Decode(responseBody)
+// so we can add the response headers at the end. Until then use DoResponseWithCustomClient.
+func DoWithCustomClient(params Params, responseBody interface{}, client *http.Client) error {
+	return DoWithCustomClientContext(context.Background(), params, responseBody, client)
 }
 
-func createRequest(params Params) (*http.Request, error) {
-	reader, err := convertToReader(params.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(params.Method, params.URL, reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range params.Headers {
-		req.Header.Set(key, value)
-	}
+// DoWithCustomClientContext is the same as DoWithCustomClient but the request
+// is bound to the provided context.
+func DoWithCustomClientContext(ctx context.Context, params Params, responseBody interface{}, client *http.Client) error {
+	return (&Client{HTTPClient: client}).Do(ctx, params, responseBody)
+}
 
-	if len(params.Query) > 0 {
-		q := req.URL.Query()
-		for key, value := range params.Query {
-			q.Add(key, value)
-		}
-		req.URL.RawQuery = q.Encode()
-	}
+// DoResponse is the same as DoContext but returns the metadata of the response.
+// If RetainResponseBody is set in the params, the response contains the raw body as well.
+func DoResponse(ctx context.Context, params Params, responseBody interface{}) (*Response, error) {
+	return getDefaultClient().DoResponse(ctx, params, responseBody)
+}
 
-	return req, nil
+// DoResponseWithCustomClient is the same as DoResponse but will make the request using the
+// supplied http.Client instead of the one of the default client.
+func DoResponseWithCustomClient(ctx context.Context, params Params, responseBody interface{}, client *http.Client) (*Response, error) {
+	return (&Client{HTTPClient: client}).DoResponse(ctx, params, responseBody)
 }
 
 // Get is a convenience wrapper for "Do" to execute GET requests
 func Get(url string, responseBody interface{}) error {
-	return Do(Params{Method: http.MethodGet, URL: url}, responseBody)
+	return GetContext(context.Background(), url, responseBody)
+}
+
+// GetContext is a convenience wrapper for "DoContext" to execute GET requests
+func GetContext(ctx context.Context, url string, responseBody interface{}) error {
+	return DoContext(ctx, Params{Method: http.MethodGet, URL: url}, responseBody)
 }
 
 // Post is a convenience wrapper for "Do" to execute POST requests
 func Post(url string, requestBody interface{}, responseBody interface{}) error {
-	return Do(Params{Method: http.MethodPost, URL: url, Body: requestBody}, responseBody)
+	return PostContext(context.Background(), url, requestBody, responseBody)
+}
+
+// PostContext is a convenience wrapper for "DoContext" to execute POST requests
+func PostContext(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
+	return DoContext(ctx, Params{Method: http.MethodPost, URL: url, Body: requestBody}, responseBody)
 }
 
 // ReformatMap converts map[string][]string to map[string]string by
@@ -204,47 +137,9 @@ func ReformatMap(inputMap map[string][]string) map[string]string {
 	return result
 }
 
-func convertToReader(body interface{}) (io.Reader, error) {
-	if body == nil {
-		return nil, nil
-	}
-
-	reader, ok := body.(io.Reader)
-	if ok {
-		return reader, nil
-	}
-
-	buffer := &bytes.Buffer{}
-	err := json.NewEncoder(buffer).Encode(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse request body to json: %w", err)
-	}
-
-	return buffer, nil
-}
-
-func selectClient(timeout time.Duration) *http.Client {
-	if timeout != 0 {
-		client := GetClient()
-		client.Timeout = timeout
-		return client
-	}
-
-	return getCachedClient()
-}
-
-func checkResponseCode(res *http.Response, expectedResponseCode int) error {
-	if expectedResponseCode != 0 && res.StatusCode != expectedResponseCode {
-		return fmt.Errorf("expected response code %d but got %d", expectedResponseCode, res.StatusCode)
-	}
-
-	if !isSuccessCode(res.StatusCode) {
-		bodyBytes, err := ioutil.ReadAll(res.Body)
-		if err != nil || len(bodyBytes) == 0 {
-			return httperrors.New(res.StatusCode, nil)
-		}
-
-		return httperrors.New(res.StatusCode, string(bodyBytes))
+func checkResponseCode(res *http.Response, expectedResponseCode int, maxErrorBodyBytes int64) error {
+	if (expectedResponseCode != 0 && res.StatusCode != expectedResponseCode) || !isSuccessCode(res.StatusCode) {
+		return newError(res, expectedResponseCode, maxErrorBodyBytes)
 	}
 
 	return nil
@@ -254,7 +149,7 @@ func isSuccessCode(statusCode int) bool {
 	return 200 <= statusCode && statusCode <= 299
 }
 
-func populateResponseHeader(res *http.Response, responseHeaderArg []http.Header) error {
+func populateResponseHeader(header http.Header, responseHeaderArg []http.Header) error {
 	if len(responseHeaderArg) == 0 { // go-staticcheck says no need to check for nil separately.
 		return nil
 	}
@@ -264,7 +159,7 @@ func populateResponseHeader(res *http.Response, responseHeaderArg []http.Header)
 	}
 
 	responseHeader := responseHeaderArg[0]
-	for key, value := range res.Header {
+	for key, value := range header {
 		responseHeader[key] = value
 	}
 
@@ -1,6 +1,8 @@
 package request
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -53,10 +55,10 @@ func TestGetClient(t *testing.T) {
 		assert.NoError(t, res.Body.Close())
 	})
 }
-func TestGetCachedClient(t *testing.T) {
+func TestGetDefaultClient(t *testing.T) {
 	t.Run("returns the same client", func(t *testing.T) {
-		client1 := getCachedClient()
-		client2 := getCachedClient()
+		client1 := getDefaultClient()
+		client2 := getDefaultClient()
 		assert.True(t, client1 == client2)
 	})
 }
@@ -228,7 +230,7 @@ func TestDoSuccessful(t *testing.T) {
 
 		err := Do(params, nil)
 		if assert.Error(t, err) {
-			assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
 		}
 	})
 
@@ -259,7 +261,7 @@ func TestDoHeaders(t *testing.T) {
 	t.Run("custom and default headers", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			assert.Equal(t, "testHeaderValue", r.Header.Get("Test-Header"))
-			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			assert.Equal(t, "application/json, application/problem+json", r.Header.Get("Accept"))
 			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
 		}))
 		defer ts.Close()
@@ -303,8 +305,9 @@ func TestDoHTTPErrors(t *testing.T) {
 
 		err := Do(params, nil)
 		assert.Error(t, err)
-		assert.IsType(t, &httperrors.HTTPError{}, err)
-		httpError := err.(*httperrors.HTTPError)
+		assert.IsType(t, &Error{}, err)
+		httpError := &httperrors.HTTPError{}
+		require.True(t, errors.As(err, &httpError))
 		assert.Equal(t, 500, httpError.StatusCode)
 		assert.Equal(t, "Internal Server Error", httpError.Message)
 	})
@@ -324,8 +327,9 @@ func TestDoHTTPErrors(t *testing.T) {
 
 		err := Do(params, nil)
 		assert.Error(t, err)
-		assert.IsType(t, &httperrors.HTTPError{}, err)
-		httpError := err.(*httperrors.HTTPError)
+		assert.IsType(t, &Error{}, err)
+		httpError := &httperrors.HTTPError{}
+		require.True(t, errors.As(err, &httpError))
 		assert.Equal(t, 400, httpError.StatusCode)
 		assert.Equal(t, "some error message", httpError.Message)
 	})
@@ -369,7 +373,115 @@ func TestDoOtherErrors(t *testing.T) {
 
 		err := Do(params, nil)
 		assert.Error(t, err)
-		assert.EqualError(t, err, "expected response code 200 but got 201")
+		assert.EqualError(t, err, "GET "+ts.URL+": expected response code 200 but got 201")
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusOK, responseErr.ExpectedStatusCode)
+		assert.Equal(t, http.StatusCreated, responseErr.StatusCode)
+	})
+}
+
+func TestDoContext(t *testing.T) {
+	t.Run("cancelled context", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(5*time.Millisecond, cancel)
+
+		err := DoContext(ctx, Params{URL: ts.URL}, nil)
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, context.Canceled))
+		}
+	})
+
+	t.Run("context deadline takes priority over the client timeout", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		client := NewClient()
+		client.Timeout = time.Millisecond
+		result := &Output{}
+		err := client.Do(ctx, Params{URL: ts.URL}, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+
+	t.Run("timeout applies if it ends before the context deadline", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		params := Params{
+			URL:     ts.URL,
+			Timeout: 1 * time.Millisecond,
+		}
+
+		err := DoContext(ctx, params, nil)
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		}
+	})
+
+	t.Run("context deadline exceeded", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+
+		_, err := DoWithStringResponseContext(ctx, Params{URL: ts.URL})
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		}
+	})
+
+	t.Run("custom client", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := DoWithCustomClientContext(ctx, Params{URL: ts.URL}, nil, GetClient())
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, context.Canceled))
+		}
+	})
+
+	t.Run("convenience wrappers", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		result := &Output{}
+		err := GetContext(context.Background(), ts.URL, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+
+		result = &Output{}
+		err = PostContext(context.Background(), ts.URL, Input{RequestValue: "someValueIn"}, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
 	})
 }
 
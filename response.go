@@ -0,0 +1,43 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Response holds the metadata of a response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Trailer holds the trailers the server sent after the body.
+	Trailer http.Header
+	// URL is the URL of the request that led to the response. It differs from
+	// the requested URL if the http client followed redirects.
+	URL   *url.URL
+	Proto string
+	// ContentLength is the length of the body as announced by the server or -1 if it is unknown.
+	ContentLength int64
+	// Duration is the time from sending the request until the response body was read.
+	Duration time.Duration
+	// RawBody holds the response body if RetainResponseBody was set in the request params.
+	RawBody []byte
+}
+
+// newResponse copies the metadata of the http response. Until the body was read,
+// the trailer only holds the keys the server announced.
+func newResponse(res *http.Response) *Response {
+	result := &Response{
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		Trailer:       res.Trailer,
+		Proto:         res.Proto,
+		ContentLength: res.ContentLength,
+	}
+
+	if res.Request != nil {
+		result.URL = res.Request.URL
+	}
+
+	return result
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoResponse(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Some-Trailer")
+		w.Header().Set("Some-Header", "someHeaderValue")
+		w.WriteHeader(http.StatusAccepted)
+		_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+		assert.NoError(t, err)
+		w.Header().Set("Some-Trailer", "someTrailerValue")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("metadata", func(t *testing.T) {
+		result := &Output{}
+		res, err := DoResponse(ctx, Params{URL: ts.URL + "/new"}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "someHeaderValue", res.Header.Get("Some-Header"))
+		assert.Equal(t, "someTrailerValue", res.Trailer.Get("Some-Trailer"))
+		assert.Equal(t, ts.URL+"/new", res.URL.String())
+		assert.Equal(t, "HTTP/1.1", res.Proto)
+		assert.Equal(t, int64(-1), res.ContentLength)
+		assert.True(t, res.Duration > 0)
+		assert.Nil(t, res.RawBody)
+	})
+
+	t.Run("retained body", func(t *testing.T) {
+		result := &Output{}
+		res, err := DoResponse(ctx, Params{URL: ts.URL + "/new", RetainResponseBody: true}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+		assert.Equal(t, `{"responseValue":"someValueOut"}`, string(res.RawBody))
+	})
+
+	t.Run("without response body", func(t *testing.T) {
+		res, err := NewClient().DoResponse(ctx, Params{URL: ts.URL + "/new"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "someTrailerValue", res.Trailer.Get("Some-Trailer"))
+	})
+
+	t.Run("does not wait for the end of an open stream", func(t *testing.T) {
+		release := make(chan struct{})
+		stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			<-release
+		}))
+		defer stream.Close()
+		defer close(release)
+
+		result := &Output{}
+		start := time.Now()
+		_, err := DoResponse(ctx, Params{URL: stream.URL, Timeout: 5 * time.Second}, result)
+		require.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+		assert.True(t, time.Since(start) < time.Second)
+	})
+
+	t.Run("custom client", func(t *testing.T) {
+		res, err := DoResponseWithCustomClient(ctx, Params{URL: ts.URL + "/old"}, nil, &http.Client{})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, ts.URL+"/new", res.URL.String())
+		assert.Equal(t, "someHeaderValue", res.Header.Get("Some-Header"))
+	})
+}
@@ -0,0 +1,285 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fastbill/go-httperrors/v2"
+)
+
+// Default values of the retry policy that are used for fields that are not set.
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// defaultRetryableStatusCodes are retried if the policy does not define its own status codes.
+var defaultRetryableStatusCodes = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy defines if and how failed requests are retried.
+// The zero value retries idempotent requests up to 3 times on
+// 502, 503 and 504 responses and on connection errors.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It doubles with every further retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+
+	// DisableJitter turns off the randomization of the delay. By default a random
+	// delay between zero and the calculated backoff is used ("full jitter").
+	DisableJitter bool
+
+	// RetryableStatusCodes are the response codes that lead to a retry.
+	RetryableStatusCodes []int
+
+	// RetryableError decides if an error that occurred while sending the request leads to a retry.
+	// By default connection resets, refused connections, unexpected EOFs and network timeouts are retried.
+	RetryableError func(err error) bool
+
+	// RetryNonIdempotent allows retrying requests with methods like POST and PATCH.
+	// By default only idempotent methods are retried.
+	RetryNonIdempotent bool
+
+	// HonorRetryAfter makes the retries wait for the time given in the Retry-After header of
+	// 429 and 503 responses. 429 responses are retried even if they are not part of the
+	// retryable status codes. If the wait would exceed the deadline of the context,
+	// the request is not retried.
+	HonorRetryAfter bool
+
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// RetryError is returned if a request failed after more than one attempt.
+// It contains the errors of all attempts.
+type RetryError struct {
+	// Attempts holds the error of every attempt in the order they were made.
+	Attempts []error
+	// Err is the error that ended the retries. It is either the error of the last
+	// attempt or the error of the context if it ended while waiting for the next attempt.
+	Err error
+}
+
+// Error lists the errors of all attempts.
+func (e *RetryError) Error() string {
+	messages := make([]string, 0, len(e.Attempts))
+	for i, err := range e.Attempts {
+		messages = append(messages, fmt.Sprintf("attempt %d: %s", i+1, err))
+	}
+
+	result := fmt.Sprintf("request failed after %d attempts: %s", len(e.Attempts), strings.Join(messages, "; "))
+	if len(e.Attempts) > 0 && e.Err != e.Attempts[len(e.Attempts)-1] {
+		result += fmt.Sprintf("; aborted: %s", e.Err)
+	}
+
+	return result
+}
+
+// Unwrap returns the error that ended the retries.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// sendWithRetries executes the request until it succeeds or the retry policy gives up.
+func (c *Client) sendWithRetries(ctx context.Context, params Params, body *requestBody, policy *RetryPolicy) (*http.Response, error) {
+	attempts := []error{}
+	for attempt := 1; ; attempt++ {
+		res, err := c.sendOnce(ctx, params, body)
+		if err == nil {
+			return res, nil
+		}
+
+		attempts = append(attempts, err)
+		if attempt >= policy.maxAttempts() || !body.replayable() || !policy.isRetryable(ctx, params.Method, err) {
+			break
+		}
+
+		delay, ok := policy.nextDelay(ctx, attempt, err)
+		if !ok {
+			break
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+
+		if waitErr := sleep(ctx, delay); waitErr != nil {
+			return nil, retryError(attempts, waitErr)
+		}
+	}
+
+	return nil, retryError(attempts, attempts[len(attempts)-1])
+}
+
+// retryError sets the number of attempts on the error of the last attempt and returns the error
+// that ended the retries. If the last attempt ended them, a single error is returned as it is.
+func retryError(attempts []error, err error) error {
+	if responseErr := asError(attempts[len(attempts)-1]); responseErr != nil {
+		responseErr.Attempts = len(attempts)
+	}
+
+	if len(attempts) == 1 && err == attempts[0] {
+		return err
+	}
+
+	return &RetryError{Attempts: attempts, Err: err}
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts == 0 {
+		return defaultMaxAttempts
+	}
+
+	return p.MaxAttempts
+}
+
+// isRetryable decides if the request should be attempted again after the given error.
+func (p *RetryPolicy) isRetryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if !p.RetryNonIdempotent && !isIdempotent(method) {
+		return false
+	}
+
+	httpErr := &httperrors.HTTPError{}
+	if errors.As(err, &httpErr) {
+		if p.HonorRetryAfter && httpErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		return p.isRetryableStatusCode(httpErr.StatusCode)
+	}
+
+	if p.RetryableError != nil {
+		return p.RetryableError(err)
+	}
+
+	return isRetryableError(err)
+}
+
+func (p *RetryPolicy) isRetryableStatusCode(statusCode int) bool {
+	statusCodes := p.RetryableStatusCodes
+	if statusCodes == nil {
+		statusCodes = defaultRetryableStatusCodes
+	}
+
+	for _, code := range statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextDelay returns the time to wait before the next attempt. If the Retry-After header
+// is honored and the wait would exceed the deadline of the context, false is returned.
+func (p *RetryPolicy) nextDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	rateLimitErr := &RateLimitError{}
+	if !p.HonorRetryAfter || !errors.As(err, &rateLimitErr) || rateLimitErr.RateLimit.RetryAfter == 0 {
+		return p.delay(attempt), true
+	}
+
+	delay := rateLimitErr.RateLimit.RetryAfter
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// delay returns the time to wait after the given attempt.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	baseDelay := p.BaseDelay
+	if baseDelay == 0 {
+		baseDelay = defaultBaseDelay
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	backoff := maxDelay
+	if shift := attempt - 1; shift < 32 && baseDelay<<shift > 0 && baseDelay<<shift < maxDelay {
+		backoff = baseDelay << shift
+	}
+
+	if p.DisableJitter {
+		return backoff
+	}
+
+	return randomDuration(backoff)
+}
+
+var (
+	jitterRand  = rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404 -- jitter does not need to be cryptographically secure
+	jitterMutex sync.Mutex
+)
+
+// randomDuration returns a random duration in [0, limit].
+func randomDuration(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(limit) + 1))
+}
+
+// isIdempotent reports whether the method is idempotent as defined in RFC 9110.
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableError reports whether the error is a temporary network problem.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	netErr := net.Error(nil)
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,229 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fastbill/go-httperrors/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	t.Run("retries until success", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err := w.Write([]byte(`{"responseValue":"someValueOut"}`))
+			assert.NoError(t, err)
+		}))
+		defer ts.Close()
+
+		retries := []int{}
+		params := Params{
+			URL: ts.URL,
+			Retry: &RetryPolicy{
+				BaseDelay: time.Millisecond,
+				OnRetry: func(attempt int, err error, delay time.Duration) {
+					retries = append(retries, attempt)
+				},
+			},
+		}
+
+		result := &Output{}
+		err := Do(params, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+		assert.Equal(t, []int{1, 2}, retries)
+	})
+
+	t.Run("reports every attempt", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer ts.Close()
+
+		params := Params{
+			URL:   ts.URL,
+			Retry: &RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond},
+		}
+
+		err := Do(params, nil)
+		retryErr := &RetryError{}
+		require.True(t, errors.As(err, &retryErr))
+		assert.Len(t, retryErr.Attempts, 4)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+		assert.Contains(t, err.Error(), "request failed after 4 attempts")
+		assert.Contains(t, err.Error(), "attempt 4: GET "+ts.URL+": 502 - Bad Gateway")
+
+		httpErr := &httperrors.HTTPError{}
+		require.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	})
+
+	t.Run("does not retry other status codes", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer ts.Close()
+
+		params := Params{
+			URL:   ts.URL,
+			Retry: &RetryPolicy{BaseDelay: time.Millisecond},
+		}
+
+		err := Do(params, nil)
+		assert.IsType(t, &Error{}, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("only idempotent methods by default", func(t *testing.T) {
+		calls := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, `{"requestValue":"someValueIn"}`+"\n", string(body))
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		params := Params{
+			URL:    ts.URL,
+			Method: http.MethodPost,
+			Body:   Input{RequestValue: "someValueIn"},
+			Retry:  &RetryPolicy{BaseDelay: time.Millisecond},
+		}
+
+		err := Do(params, nil)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		params.Retry.RetryNonIdempotent = true
+		err = Do(params, nil)
+		assert.Error(t, err)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("retries connection errors", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		calls := int32(0)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				atomic.AddInt32(&calls, 1)
+				// Read the request before closing so the client sees an unexpected EOF.
+				_, _ = http.ReadRequest(bufio.NewReader(conn))
+				_ = conn.Close()
+			}
+		}()
+		defer listener.Close()
+
+		params := Params{
+			URL:   "http://" + listener.Addr().String(),
+			Retry: &RetryPolicy{BaseDelay: time.Millisecond},
+		}
+
+		err = Do(params, nil)
+		assert.True(t, errors.As(err, new(*RetryError)))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("custom retryable errors", func(t *testing.T) {
+		attempts := 0
+		client := NewClient()
+		client.Retry = &RetryPolicy{
+			BaseDelay: time.Millisecond,
+			RetryableError: func(err error) bool {
+				attempts++
+				return strings.Contains(err.Error(), "unsupported protocol scheme")
+			},
+		}
+
+		err := client.Get(context.Background(), "foo://bar", nil)
+		assert.True(t, errors.As(err, new(*RetryError)))
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		params := Params{
+			URL:   ts.URL,
+			Retry: &RetryPolicy{MaxAttempts: 100, BaseDelay: time.Second, DisableJitter: true},
+		}
+
+		err := DoContext(ctx, params, nil)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Contains(t, err.Error(), "aborted: context deadline exceeded")
+	})
+
+	t.Run("counts the attempts when the context ends while waiting", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		params := Params{
+			URL: ts.URL,
+			Retry: &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, OnRetry: func(attempt int, err error, delay time.Duration) {
+				if attempt == 2 {
+					cancel()
+				}
+			}},
+		}
+
+		err := DoContext(ctx, params, nil)
+		retryErr := &RetryError{}
+		require.True(t, errors.As(err, &retryErr))
+		assert.True(t, errors.Is(err, context.Canceled))
+		require.Len(t, retryErr.Attempts, 2)
+		responseErr := &Error{}
+		require.True(t, errors.As(retryErr.Attempts[1], &responseErr))
+		assert.Equal(t, 2, responseErr.Attempts)
+	})
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, DisableJitter: true}
+	assert.Equal(t, 10*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 20*time.Millisecond, policy.delay(2))
+	assert.Equal(t, 40*time.Millisecond, policy.delay(3))
+	assert.Equal(t, 50*time.Millisecond, policy.delay(4))
+	assert.Equal(t, 50*time.Millisecond, policy.delay(100))
+
+	policy.DisableJitter = false
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(3)
+		assert.True(t, delay >= 0 && delay <= 40*time.Millisecond)
+	}
+}
@@ -0,0 +1,264 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventStreamContentType is the media type of server-sent events.
+const eventStreamContentType = "text/event-stream"
+
+// defaultReconnectDelay is the time to wait before reconnecting if the server did not send a retry interval.
+const defaultReconnectDelay = 3 * time.Second
+
+// errStreamBroken marks errors of reading the stream, after which the event source reconnects.
+var errStreamBroken = errors.New("event stream broken")
+
+// Event is a server-sent event.
+type Event struct {
+	// ID is the last event ID the server sent. It is kept for the following events unless the server changes it.
+	ID string
+	// Event is the type of the event, it defaults to "message".
+	Event string
+	// Data holds the data lines of the event joined with a newline.
+	Data string
+	// Retry is the reconnection interval the server sent with the event or zero if it did not send one.
+	Retry time.Duration
+}
+
+// DecodeJSON decodes the data of the event as JSON into the value.
+func (e Event) DecodeJSON(v interface{}) error {
+	return json.Unmarshal([]byte(e.Data), v)
+}
+
+// EventSource receives server-sent events. If the connection is lost, it reconnects automatically
+// and sends the ID of the last event it received in the Last-Event-ID header.
+// An EventSource must not be used for more than one subscription at the same time.
+type EventSource struct {
+	// Client is used to send the requests. If it is nil, the default client is used.
+	// The timeout of the client is not applied, the connection stays open until the context is done.
+	Client *Client
+
+	// Params are used for every connection. The Accept and Last-Event-ID headers are set automatically.
+	// The Timeout is ignored like the one of the client, use the context to end the subscription.
+	Params Params
+
+	// ReconnectDelay is the time to wait before reconnecting. It defaults to 3 seconds
+	// and is replaced by the retry interval the server sends.
+	ReconnectDelay time.Duration
+
+	// LastEventID is the ID of the last event that was received. If it is set before subscribing,
+	// the server can resume the stream after that event.
+	LastEventID string
+}
+
+// Subscribe connects to the URL of the params with the default client and calls the handler for every event.
+// It reconnects if the connection was lost and blocks until the context is done, the handler returns an error
+// or the server tells it to stop, see EventSource.Subscribe. The Timeout of the params is ignored.
+func Subscribe(ctx context.Context, params Params, handler func(event Event) error) error {
+	source := &EventSource{Params: params}
+	return source.Subscribe(ctx, handler)
+}
+
+// Subscribe calls the handler for every event. It reconnects if the connection was lost and blocks until
+// the context is done or the handler returns an error, which is then returned. It also returns if the server
+// responds with an error status code, a content type other than text/event-stream or with status code 204,
+// which tells the client to stop reconnecting.
+func (s *EventSource) Subscribe(ctx context.Context, handler func(event Event) error) error {
+	for {
+		reconnect, err := s.connect(ctx, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !reconnect {
+			return err
+		}
+
+		err = sleep(ctx, s.reconnectDelay())
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Events is the same as Subscribe but delivers the events over a channel. The channel is closed once the
+// subscription ended, afterwards the error channel yields the error that ended it. If the events are no longer
+// read, the context must be cancelled to end the subscription and close the connection.
+func (s *EventSource) Events(ctx context.Context) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		err := s.Subscribe(ctx, func(event Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(events)
+		errs <- err
+	}()
+
+	return events, errs
+}
+
+// connect opens a single connection and dispatches the events until the stream ends.
+// It reports whether a reconnect should be attempted.
+func (s *EventSource) connect(ctx context.Context, handler func(event Event) error) (reconnect bool, returnErr error) {
+	client := s.Client
+	if client == nil {
+		client = getDefaultClient()
+	}
+
+	res, err := client.send(ctx, s.params())
+	if err != nil {
+		var responseErr *Error
+		return !errors.As(err, &responseErr), err
+	}
+
+	defer func() {
+		if cErr := res.Body.Close(); cErr != nil && returnErr == nil {
+			returnErr = cErr
+		}
+	}()
+
+	if res.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || mediaType != eventStreamContentType {
+		return false, fmt.Errorf("expected content type %s but got %q", eventStreamContentType, res.Header.Get("Content-Type"))
+	}
+
+	err = s.read(res.Body, handler)
+	if err != nil && !errors.Is(err, errStreamBroken) {
+		return false, err
+	}
+
+	return true, err
+}
+
+// read parses the stream and calls the handler for every complete event.
+func (s *EventSource) read(body io.Reader, handler func(event Event) error) error {
+	parser := &eventParser{lastEventID: s.LastEventID}
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", errStreamBroken, err)
+		}
+
+		event, ok := parser.parseLine(strings.TrimRight(line, "\r\n"))
+		s.LastEventID = parser.lastEventID
+		if parser.retry > 0 {
+			s.ReconnectDelay = parser.retry
+		}
+		if !ok {
+			continue
+		}
+
+		err = handler(event)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *EventSource) params() Params {
+	params := s.Params
+	params.Headers = map[string]string{}
+	for key, value := range s.Params.Headers {
+		params.Headers[key] = value
+	}
+
+	params.Headers["Accept"] = eventStreamContentType
+	params.Headers["Cache-Control"] = "no-cache"
+	if s.LastEventID != "" {
+		params.Headers["Last-Event-ID"] = s.LastEventID
+	}
+
+	return params
+}
+
+func (s *EventSource) reconnectDelay() time.Duration {
+	if s.ReconnectDelay <= 0 {
+		return defaultReconnectDelay
+	}
+
+	return s.ReconnectDelay
+}
+
+// eventParser collects the fields of an event as defined in the HTML standard.
+type eventParser struct {
+	lastEventID string
+	eventType   string
+	data        []string
+	retry       time.Duration
+}
+
+// parseLine processes a single line. If the line completes an event, the event is returned.
+func (p *eventParser) parseLine(line string) (Event, bool) {
+	if line == "" {
+		return p.dispatch()
+	}
+
+	if strings.HasPrefix(line, ":") {
+		return Event{}, false
+	}
+
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "event":
+		p.eventType = value
+	case "data":
+		p.data = append(p.data, value)
+	case "id":
+		if !strings.Contains(value, "\x00") {
+			p.lastEventID = value
+		}
+	case "retry":
+		if milliseconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+			p.retry = time.Duration(milliseconds) * time.Millisecond
+		}
+	}
+
+	return Event{}, false
+}
+
+// dispatch returns the collected event and resets the fields. Events without data are not dispatched.
+func (p *eventParser) dispatch() (Event, bool) {
+	event := Event{
+		ID:    p.lastEventID,
+		Event: p.eventType,
+		Data:  strings.Join(p.data, "\n"),
+		Retry: p.retry,
+	}
+	hasData := len(p.data) > 0
+
+	p.eventType = ""
+	p.data = nil
+	p.retry = 0
+
+	if event.Event == "" {
+		event.Event = "message"
+	}
+
+	return event, hasData
+}
@@ -0,0 +1,191 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventParser(t *testing.T) {
+	parser := &eventParser{}
+	events := []Event{}
+	stream := ": comment\n" +
+		"event: update\n" +
+		"data: first line\n" +
+		"data:second line\n" +
+		"id: 1\n" +
+		"retry: 250\n" +
+		"unknown: ignored\n" +
+		"\n" +
+		"id: 2\n" +
+		"\n" +
+		"data\n" +
+		"\n" +
+		"id: bad\x00id\n" +
+		"data: {\"value\":42}\n" +
+		"\n"
+	for _, line := range strings.Split(stream, "\n") {
+		if event, ok := parser.parseLine(line); ok {
+			events = append(events, event)
+		}
+	}
+
+	assert.Equal(t, []Event{
+		{ID: "1", Event: "update", Data: "first line\nsecond line", Retry: 250 * time.Millisecond},
+		{ID: "2", Event: "message", Data: ""},
+		{ID: "2", Event: "message", Data: `{"value":42}`},
+	}, events)
+
+	value := map[string]int{}
+	require.NoError(t, events[2].DecodeJSON(&value))
+	assert.Equal(t, 42, value["value"])
+}
+
+func TestEventSource(t *testing.T) {
+	t.Run("reconnects with the last event ID", func(t *testing.T) {
+		connections := int32(0)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "text/event-stream")
+
+			switch atomic.AddInt32(&connections, 1) {
+			case 1:
+				assert.Equal(t, "", r.Header.Get("Last-Event-ID"))
+				_, _ = fmt.Fprint(w, "retry: 10\nid: 1\ndata: one\n\nid: 2\ndata: two\n\ndata: incomplete")
+			case 2:
+				assert.Equal(t, "2", r.Header.Get("Last-Event-ID"))
+				_, _ = fmt.Fprint(w, "id: 3\nevent: done\ndata: three\n\n")
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+		defer ts.Close()
+
+		source := &EventSource{Params: Params{URL: ts.URL, Headers: map[string]string{"Authorization": "Bearer token"}}}
+		events := []Event{}
+		err := source.Subscribe(context.Background(), func(event Event) error {
+			events = append(events, event)
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []Event{
+			{ID: "1", Event: "message", Data: "one", Retry: 10 * time.Millisecond},
+			{ID: "2", Event: "message", Data: "two"},
+			{ID: "3", Event: "done", Data: "three"},
+		}, events)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&connections))
+		assert.Equal(t, "3", source.LastEventID)
+		assert.Equal(t, 10*time.Millisecond, source.ReconnectDelay)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: one\n\ndata: two\n\n")
+		}))
+		defer ts.Close()
+
+		handlerErr := errors.New("stop")
+		err := Subscribe(context.Background(), Params{URL: ts.URL}, func(event Event) error {
+			return handlerErr
+		})
+		assert.Equal(t, handlerErr, err)
+	})
+
+	t.Run("channel", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: one\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		source := &EventSource{Params: Params{URL: ts.URL}}
+		events, errs := source.Events(ctx)
+
+		event := <-events
+		assert.Equal(t, "one", event.Data)
+		cancel()
+
+		_, open := <-events
+		assert.False(t, open)
+		assert.True(t, errors.Is(<-errs, context.Canceled))
+	})
+
+	t.Run("channel consumer stops reading", func(t *testing.T) {
+		closed := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(closed)
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; r.Context().Err() == nil; i++ {
+				_, _ = fmt.Fprintf(w, "data: %d\n\n", i)
+				w.(http.Flusher).Flush()
+				time.Sleep(time.Millisecond)
+			}
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		source := &EventSource{Params: Params{URL: ts.URL}}
+		events, errs := source.Events(ctx)
+		assert.Equal(t, "0", (<-events).Data)
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-errs:
+			assert.True(t, errors.Is(err, context.Canceled))
+		case <-time.After(5 * time.Second):
+			t.Fatal("the subscription did not end")
+		}
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the connection was not closed")
+		}
+	})
+
+	t.Run("does not reconnect on errors", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/json" {
+				w.Header().Set("Content-Type", "application/json")
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer ts.Close()
+
+		handler := func(event Event) error { return nil }
+		err := Subscribe(context.Background(), Params{URL: ts.URL}, handler)
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusUnauthorized, responseErr.StatusCode)
+
+		err = Subscribe(context.Background(), Params{URL: ts.URL + "/json"}, handler)
+		assert.EqualError(t, err, `expected content type text/event-stream but got "application/json"`)
+	})
+
+	t.Run("reconnects after connection errors", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		source := &EventSource{Params: Params{URL: ts.URL}, ReconnectDelay: 5 * time.Millisecond}
+		err := source.Subscribe(ctx, func(event Event) error { return nil })
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
@@ -0,0 +1,105 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DoStream executes the request with the default client and passes the response body to the consumer.
+// The body is not buffered, so it can be used for large downloads. Status code checking and closing
+// the body are taken care of. Note that the timeout also applies to reading the body.
+func DoStream(ctx context.Context, params Params, consume func(body io.Reader) error) (*Response, error) {
+	return getDefaultClient().DoStream(ctx, params, consume)
+}
+
+// DoStreamTo is the same as DoStream but copies the response body to the writer.
+func DoStreamTo(ctx context.Context, params Params, w io.Writer) (*Response, error) {
+	return getDefaultClient().DoStreamTo(ctx, params, w)
+}
+
+// DoStream executes the request and passes the response body to the consumer.
+// The error of the consumer is returned together with the response metadata.
+func (c *Client) DoStream(ctx context.Context, params Params, consume func(body io.Reader) error) (*Response, error) {
+	return c.receive(ctx, params, func(result *Response, body io.Reader) error {
+		return consume(body)
+	})
+}
+
+// DoStreamTo is the same as DoStream but copies the response body to the writer.
+func (c *Client) DoStreamTo(ctx context.Context, params Params, w io.Writer) (*Response, error) {
+	return c.DoStream(ctx, params, func(body io.Reader) error {
+		_, err := io.Copy(w, body)
+		return err
+	})
+}
+
+// receive executes the request and passes the response body to the consumer. If the consumer fails,
+// the response is returned together with the error.
+func (c *Client) receive(ctx context.Context, params Params, consume func(result *Response, body io.Reader) error) (result *Response, returnErr error) {
+	start := time.Now()
+	res, cancel, err := c.open(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	defer func() {
+		if cErr := res.Body.Close(); cErr != nil && returnErr == nil {
+			returnErr = cErr
+		}
+	}()
+
+	result = newResponse(res)
+	err = consume(result, c.responseBodyReader(res, params))
+	result.Trailer = res.Trailer
+	result.Duration = time.Since(start)
+
+	return result, err
+}
+
+// open sends the request and returns the response. The returned function releases the
+// context of the request, it must be called once the response body was closed.
+func (c *Client) open(ctx context.Context, params Params) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := c.withTimeout(ctx, params.Timeout)
+	res, err := c.send(ctx, params)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return res, cancel, nil
+}
+
+// responseBodyReader applies the progress callback and the size limit to the response body.
+func (c *Client) responseBodyReader(res *http.Response, params Params) io.Reader {
+	reader := io.Reader(res.Body)
+	if limit := c.maxResponseBytes(params); limit > 0 {
+		reader = &maxBytesReader{reader: reader, limit: limit}
+	}
+
+	if params.OnDownloadProgress != nil {
+		reader = &progressReader{reader: reader, total: res.ContentLength, onProgress: params.OnDownloadProgress}
+	}
+
+	return reader
+}
+
+// progressReader reports the number of bytes read so far together with the expected total, which is -1 if it is unknown.
+type progressReader struct {
+	reader     io.Reader
+	total      int64
+	read       int64
+	onProgress func(read int64, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if n > 0 {
+		r.onProgress(r.read, r.total)
+	}
+
+	return n, err
+}
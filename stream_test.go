@@ -0,0 +1,100 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoStream(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		_, _ = w.Write([]byte(content))
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("writer", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		res, err := DoStreamTo(ctx, Params{URL: ts.URL}, buffer)
+		require.NoError(t, err)
+		assert.Equal(t, content, buffer.String())
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/pdf", res.Header.Get("Content-Type"))
+	})
+
+	t.Run("consumer", func(t *testing.T) {
+		var received string
+		_, err := NewClient().DoStream(ctx, Params{URL: ts.URL}, func(body io.Reader) error {
+			data, err := ioutil.ReadAll(body)
+			received = string(data)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, content, received)
+	})
+
+	t.Run("consumer error", func(t *testing.T) {
+		consumerErr := errors.New("disk full")
+		res, err := DoStream(ctx, Params{URL: ts.URL}, func(body io.Reader) error {
+			return consumerErr
+		})
+		assert.Equal(t, consumerErr, err)
+		require.NotNil(t, res)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		_, err := DoStream(ctx, Params{URL: ts.URL + "/error"}, func(body io.Reader) error {
+			t.Error("consumer must not be called")
+			return nil
+		})
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusNotFound, responseErr.StatusCode)
+		assert.Equal(t, []byte("not found"), responseErr.Body)
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		var received, total int64
+		params := Params{URL: ts.URL, OnDownloadProgress: func(r int64, t int64) {
+			received, total = r, t
+		}}
+		_, err := DoStreamTo(ctx, params, ioutil.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), received)
+		assert.Equal(t, int64(len(content)), total)
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		_, err := DoStreamTo(ctx, Params{URL: ts.URL, MaxResponseBytes: 100}, buffer)
+		assert.True(t, errors.Is(err, ErrResponseTooLarge))
+		assert.Equal(t, content[:100], buffer.String())
+
+		buffer.Reset()
+		_, err = DoStreamTo(ctx, Params{URL: ts.URL, MaxResponseBytes: int64(len(content))}, buffer)
+		require.NoError(t, err)
+		assert.Equal(t, content, buffer.String())
+
+		_, err = DoWithStringResponse(Params{URL: ts.URL, MaxResponseBytes: 100})
+		assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	})
+}
@@ -0,0 +1,41 @@
+package request
+
+import (
+	"net/http"
+	"time"
+)
+
+// TransportConfig holds the connection pool settings of a transport.
+// Zero values keep the settings of http.DefaultTransport.
+type TransportConfig struct {
+	// MaxIdleConns limits the number of idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits the number of idle connections kept per host.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the total number of connections per host.
+	MaxConnsPerHost int
+	// IdleConnTimeout is the time an idle connection is kept before it is closed.
+	IdleConnTimeout time.Duration
+}
+
+// sharedTransport is the transport used by all clients created by the package.
+var sharedTransport = NewTransport(TransportConfig{})
+
+// NewTransport returns a copy of http.DefaultTransport with the given connection pool settings applied.
+func NewTransport(config TransportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.MaxIdleConns != 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost != 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	return transport
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTransport(t *testing.T) {
+	t.Run("applies the pool settings", func(t *testing.T) {
+		transport := NewTransport(TransportConfig{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 5,
+			MaxConnsPerHost:     20,
+			IdleConnTimeout:     time.Minute,
+		})
+		assert.Equal(t, 10, transport.MaxIdleConns)
+		assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 20, transport.MaxConnsPerHost)
+		assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	})
+
+	t.Run("keeps the defaults for zero values", func(t *testing.T) {
+		defaultTransport := http.DefaultTransport.(*http.Transport)
+		transport := NewTransport(TransportConfig{})
+		assert.Equal(t, defaultTransport.MaxIdleConns, transport.MaxIdleConns)
+		assert.Equal(t, defaultTransport.IdleConnTimeout, transport.IdleConnTimeout)
+		assert.True(t, defaultTransport != transport)
+	})
+}
+
+func TestSharedTransport(t *testing.T) {
+	assert.True(t, GetClient().Transport == NewClient().HTTPClient.Transport)
+
+	transport := NewTransport(TransportConfig{MaxConnsPerHost: 1})
+	client := NewClientWithTransport(transport)
+	assert.True(t, client.HTTPClient.Transport == transport)
+	assert.Equal(t, time.Duration(0), client.HTTPClient.Timeout)
+	assert.Equal(t, defaultTimeout, client.Timeout)
+}
+
+func TestTimeoutViaContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	t.Run("with custom client", func(t *testing.T) {
+		httpClient := &http.Client{}
+		err := DoWithCustomClient(Params{URL: ts.URL, Timeout: time.Millisecond}, nil, httpClient)
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		}
+		assert.Equal(t, time.Duration(0), httpClient.Timeout)
+	})
+
+	t.Run("params timeout is longer than the client timeout", func(t *testing.T) {
+		client := NewClient()
+		client.Timeout = time.Millisecond
+		err := client.Do(context.Background(), Params{URL: ts.URL, Timeout: time.Second}, nil)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSetDefaultClient(t *testing.T) {
+	previous := getDefaultClient()
+	defer SetDefaultClient(previous)
+
+	client := NewClient()
+	SetDefaultClient(client)
+	assert.True(t, getDefaultClient() == client)
+
+	t.Run("without http client", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"responseValue":"someValueOut"}`))
+		}))
+		defer ts.Close()
+
+		SetDefaultClient(&Client{BaseURL: ts.URL})
+		result := &Output{}
+		err := Do(Params{URL: "/path"}, result)
+		assert.NoError(t, err)
+		assert.Equal(t, "someValueOut", result.ResponseValue)
+	})
+}
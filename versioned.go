@@ -0,0 +1,166 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// defaultModifyAttempts is the number of attempts ModifyVersioned makes if none are specified.
+const defaultModifyAttempts = 3
+
+// ErrPreconditionFailed is matched by errors.Is for every PreconditionFailedError.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrMissingVersion is returned if a versioned update was attempted without an ETag or Last-Modified value.
+var ErrMissingVersion = errors.New("the resource has neither an ETag nor a Last-Modified header")
+
+// PreconditionFailedError is returned for responses with status code 412, which means the resource
+// was changed since the version that was sent in the If-Match or If-Unmodified-Since header.
+// It wraps the Error of the response.
+type PreconditionFailedError struct {
+	Err error
+}
+
+// Error returns the message of the wrapped error.
+func (e *PreconditionFailedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped Error.
+func (e *PreconditionFailedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrPreconditionFailed.
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// Version identifies a state of a resource by the ETag or Last-Modified header of the response.
+type Version struct {
+	ETag         string
+	LastModified string
+}
+
+// versionOf returns the version of the response.
+func versionOf(header http.Header) Version {
+	return Version{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// IsZero reports whether the version has neither an ETag nor a Last-Modified value.
+func (v Version) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// apply returns a copy of the params with the If-Match header or, if there is no ETag,
+// the If-Unmodified-Since header.
+func (v Version) apply(params Params) Params {
+	headers := map[string]string{}
+	for key, value := range params.Headers {
+		headers[key] = value
+	}
+
+	if v.ETag != "" {
+		headers["If-Match"] = v.ETag
+	} else {
+		headers["If-Unmodified-Since"] = v.LastModified
+	}
+
+	params.Headers = headers
+	return params
+}
+
+// Versioned holds a decoded resource together with its version.
+type Versioned[T any] struct {
+	*TypedResponse[T]
+	Version Version
+}
+
+// GetVersioned executes a GET request with the default client and returns the decoded resource together with its version.
+func GetVersioned[T any](ctx context.Context, params Params) (*Versioned[T], error) {
+	return GetVersionedWithClient[T](ctx, getDefaultClient(), params)
+}
+
+// GetVersionedWithClient is the same as GetVersioned but uses the provided client.
+func GetVersionedWithClient[T any](ctx context.Context, client *Client, params Params) (*Versioned[T], error) {
+	params.Method = http.MethodGet
+	params.Body = nil
+	return doVersioned[T](ctx, client, params)
+}
+
+// UpdateVersioned sends the body of the params with the default client only if the resource still has the given version.
+// The method defaults to PUT. If the resource was changed in the meantime, the server responds with status code 412
+// and a PreconditionFailedError is returned. The result holds the decoded response body and the new version if the
+// server sent one.
+func UpdateVersioned[T any](ctx context.Context, params Params, version Version) (*Versioned[T], error) {
+	return UpdateVersionedWithClient[T](ctx, getDefaultClient(), params, version)
+}
+
+// UpdateVersionedWithClient is the same as UpdateVersioned but uses the provided client.
+func UpdateVersionedWithClient[T any](ctx context.Context, client *Client, params Params, version Version) (*Versioned[T], error) {
+	if version.IsZero() {
+		return nil, ErrMissingVersion
+	}
+
+	if params.Method == "" {
+		params.Method = http.MethodPut
+	}
+
+	return doVersioned[T](ctx, client, version.apply(params))
+}
+
+// ModifyVersioned loads the resource from the URL of the params with the default client, applies the mutation and
+// sends the result back with the version of the loaded resource as precondition. If the resource was changed in the
+// meantime, it is loaded again and the mutation is reapplied. This is repeated until the update succeeded or the
+// number of attempts is reached, which defaults to 3. The method of the params is used for the update and defaults to PUT,
+// the body of the params is replaced by the mutated resource. Errors of the mutation are returned as they are.
+func ModifyVersioned[T any](ctx context.Context, params Params, attempts int, mutate func(value *T) error) (*Versioned[T], error) {
+	return ModifyVersionedWithClient[T](ctx, getDefaultClient(), params, attempts, mutate)
+}
+
+// ModifyVersionedWithClient is the same as ModifyVersioned but uses the provided client.
+func ModifyVersionedWithClient[T any](ctx context.Context, client *Client, params Params, attempts int, mutate func(value *T) error) (*Versioned[T], error) {
+	if attempts < 1 {
+		attempts = defaultModifyAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		var result *Versioned[T]
+		result, err = modifyOnce[T](ctx, client, params, mutate)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return result, err
+		}
+	}
+
+	return nil, err
+}
+
+// modifyOnce loads the resource, applies the mutation and sends the update.
+func modifyOnce[T any](ctx context.Context, client *Client, params Params, mutate func(value *T) error) (*Versioned[T], error) {
+	current, err := GetVersionedWithClient[T](ctx, client, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mutate(&current.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Body = current.Body
+	return UpdateVersionedWithClient[T](ctx, client, params, current.Version)
+}
+
+func doVersioned[T any](ctx context.Context, client *Client, params Params) (*Versioned[T], error) {
+	res, err := DoJSONWithClient[T](ctx, client, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Versioned[T]{TypedResponse: res, Version: versionOf(res.Header)}, nil
+}
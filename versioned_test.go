@@ -0,0 +1,147 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type account struct {
+	Name    string `json:"name"`
+	Balance int    `json:"balance"`
+}
+
+// versionedServer holds a single account and rejects updates that do not match its current ETag.
+type versionedServer struct {
+	mutex   sync.Mutex
+	version int
+	account account
+	// conflicts is the number of updates that are rejected because of a concurrent change.
+	conflicts int
+}
+
+func (s *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		if s.conflicts > 0 {
+			s.conflicts--
+			s.version++
+			s.account.Balance += 100
+		}
+		if r.Header.Get("If-Match") != s.etag() {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&s.account); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.version++
+	}
+
+	w.Header().Set("ETag", s.etag())
+	_ = json.NewEncoder(w).Encode(s.account)
+}
+
+func (s *versionedServer) etag() string {
+	return fmt.Sprintf(`"%d"`, s.version)
+}
+
+func TestVersioned(t *testing.T) {
+	server := &versionedServer{account: account{Name: "test", Balance: 10}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	ctx := context.Background()
+
+	t.Run("get and update", func(t *testing.T) {
+		current, err := GetVersioned[account](ctx, Params{URL: ts.URL})
+		require.NoError(t, err)
+		assert.Equal(t, account{Name: "test", Balance: 10}, current.Body)
+		assert.Equal(t, Version{ETag: `"0"`}, current.Version)
+
+		current.Body.Balance = 20
+		updated, err := UpdateVersioned[account](ctx, Params{URL: ts.URL, Body: current.Body}, current.Version)
+		require.NoError(t, err)
+		assert.Equal(t, 20, updated.Body.Balance)
+		assert.Equal(t, Version{ETag: `"1"`}, updated.Version)
+
+		_, err = UpdateVersioned[account](ctx, Params{URL: ts.URL, Method: http.MethodPatch, Body: current.Body}, current.Version)
+		assert.True(t, errors.Is(err, ErrPreconditionFailed))
+		responseErr := &Error{}
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusPreconditionFailed, responseErr.StatusCode)
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		_, err := UpdateVersioned[account](ctx, Params{URL: ts.URL}, Version{})
+		assert.Equal(t, ErrMissingVersion, err)
+	})
+
+	t.Run("modify reapplies the mutation", func(t *testing.T) {
+		server.mutex.Lock()
+		server.account.Balance = 0
+		server.conflicts = 2
+		server.mutex.Unlock()
+
+		calls := 0
+		result, err := ModifyVersioned(ctx, Params{URL: ts.URL}, 0, func(value *account) error {
+			calls++
+			value.Balance += 5
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 205, result.Body.Balance)
+	})
+
+	t.Run("modify gives up", func(t *testing.T) {
+		server.mutex.Lock()
+		server.conflicts = 2
+		server.mutex.Unlock()
+
+		_, err := ModifyVersionedWithClient(ctx, NewClient(), Params{URL: ts.URL}, 2, func(value *account) error {
+			return nil
+		})
+		assert.True(t, errors.Is(err, ErrPreconditionFailed))
+	})
+
+	t.Run("mutation error", func(t *testing.T) {
+		mutationErr := errors.New("insufficient balance")
+		_, err := ModifyVersioned(ctx, Params{URL: ts.URL}, 0, func(value *account) error {
+			return mutationErr
+		})
+		assert.Equal(t, mutationErr, err)
+	})
+}
+
+func TestVersionLastModified(t *testing.T) {
+	lastModified := "Wed, 01 Jun 2022 12:00:00 GMT"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.Header.Get("If-Unmodified-Since") != lastModified {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		assert.Empty(t, r.Header.Get("If-Match"))
+		w.Header().Set("Last-Modified", lastModified)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	current, err := GetVersioned[account](context.Background(), Params{URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, Version{LastModified: lastModified}, current.Version)
+
+	_, err = UpdateVersioned[account](context.Background(), Params{URL: ts.URL, Body: account{}}, current.Version)
+	assert.NoError(t, err)
+}